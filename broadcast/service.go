@@ -0,0 +1,461 @@
+package broadcast
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/locale"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateShare    = 0.5
+	defaultBatchSize    = 500
+	defaultLeaseTimeout = time.Minute
+)
+
+type runningBroadcast struct {
+	cancel     context.CancelFunc
+	stopStatus storage.BroadcastStatus
+	done       chan struct{}
+}
+
+// BroadcastService sends one message template to a large amount of recipients. Broadcast uses only
+// configured share of the global rate limit and saves its progress, so it can be paused and resumed
+// after restart.
+type BroadcastService struct {
+	cfg            config.BroadcastConfig
+	storage        storage.BroadcastStorage
+	telegramClient *client.TelegramClient
+	locales        *locale.LocalizationProvider
+	owner          string
+
+	mu      sync.Mutex
+	running map[string]*runningBroadcast
+}
+
+func NewBroadcastService(
+	cfg config.BroadcastConfig,
+	broadcastStorage storage.BroadcastStorage,
+	telegramClient *client.TelegramClient,
+	locales *locale.LocalizationProvider,
+) *BroadcastService {
+	return &BroadcastService{
+		cfg:            withDefaults(cfg),
+		storage:        broadcastStorage,
+		telegramClient: telegramClient,
+		locales:        locales,
+		owner:          randomOwnerID(),
+		running:        make(map[string]*runningBroadcast),
+	}
+}
+
+// withDefaults replaces zero and out of range values of the config with defaults.
+func withDefaults(cfg config.BroadcastConfig) config.BroadcastConfig {
+	if cfg.RateShare <= 0 || cfg.RateShare > 1 {
+		cfg.RateShare = defaultRateShare
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+
+	if cfg.LeaseTimeout <= 0 {
+		cfg.LeaseTimeout = defaultLeaseTimeout
+	}
+
+	return cfg
+}
+
+// Start creates the broadcast and sends it in background until ctx is done. Broadcast interrupted
+// by ctx stays in running status and can be continued with Resume.
+func (s *BroadcastService) Start(ctx context.Context, broadcastID string, template storage.BroadcastTemplate, source RecipientSource) error {
+	if _, err := s.storage.GetBroadcast(ctx, broadcastID); !errors.Is(err, domain.ErrorBroadcastNotFound) {
+		if err != nil {
+			return err
+		}
+
+		return domain.ErrorBroadcastIsRunning
+	}
+
+	now := time.Now()
+
+	info := &storage.BroadcastInfo{
+		ID:        broadcastID,
+		Status:    storage.BroadcastStatusRunning,
+		Template:  template,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.storage.SaveBroadcast(ctx, info); err != nil {
+		return err
+	}
+
+	return s.launch(ctx, info, source)
+}
+
+// Resume continues paused or interrupted broadcast from the saved position. The broadcast running in another
+// process is resumed only after its heartbeat is older than the lease timeout, e.g. after the process crashed.
+func (s *BroadcastService) Resume(ctx context.Context, broadcastID string, source RecipientSource) error {
+	info, err := s.storage.GetBroadcast(ctx, broadcastID)
+
+	if err != nil {
+		return err
+	}
+
+	switch info.Status {
+	case storage.BroadcastStatusCompleted, storage.BroadcastStatusCancelled:
+		return domain.ErrorBroadcastIsFinished
+	}
+
+	if info.Status == storage.BroadcastStatusRunning && info.Owner != s.owner && time.Since(info.HeartbeatAt) < s.cfg.LeaseTimeout {
+		return domain.ErrorBroadcastIsRunning
+	}
+
+	info.Status = storage.BroadcastStatusRunning
+	info.LastError = ""
+
+	return s.launch(ctx, info, source)
+}
+
+func (s *BroadcastService) Pause(ctx context.Context, broadcastID string) error {
+	return s.stop(ctx, broadcastID, storage.BroadcastStatusPaused)
+}
+
+func (s *BroadcastService) Cancel(ctx context.Context, broadcastID string) error {
+	return s.stop(ctx, broadcastID, storage.BroadcastStatusCancelled)
+}
+
+func (s *BroadcastService) GetProgress(ctx context.Context, broadcastID string) (*storage.BroadcastInfo, error) {
+	return s.storage.GetBroadcast(ctx, broadcastID)
+}
+
+// GetUnfinished returns broadcasts interrupted by restart or paused, they can be continued with Resume.
+func (s *BroadcastService) GetUnfinished(ctx context.Context) ([]storage.BroadcastInfo, error) {
+	broadcasts, err := s.storage.GetBroadcasts(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	unfinished := make([]storage.BroadcastInfo, 0, len(broadcasts))
+
+	for _, broadcast := range broadcasts {
+		if broadcast.Status == storage.BroadcastStatusRunning || broadcast.Status == storage.BroadcastStatusPaused {
+			unfinished = append(unfinished, broadcast)
+		}
+	}
+
+	return unfinished, nil
+}
+
+func (s *BroadcastService) launch(ctx context.Context, info *storage.BroadcastInfo, source RecipientSource) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.running[info.ID]; ok {
+		return domain.ErrorBroadcastIsRunning
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+
+	broadcast := &runningBroadcast{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	s.running[info.ID] = broadcast
+
+	go s.run(runCtx, info, source, broadcast)
+
+	return nil
+}
+
+func (s *BroadcastService) stop(ctx context.Context, broadcastID string, status storage.BroadcastStatus) error {
+	s.mu.Lock()
+	broadcast, ok := s.running[broadcastID]
+
+	if ok {
+		broadcast.stopStatus = status
+		broadcast.cancel()
+	}
+	s.mu.Unlock()
+
+	if ok {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-broadcast.done:
+			return nil
+		}
+	}
+
+	info, err := s.storage.GetBroadcast(ctx, broadcastID)
+
+	if err != nil {
+		return err
+	}
+
+	switch info.Status {
+	case storage.BroadcastStatusCompleted, storage.BroadcastStatusCancelled:
+		return domain.ErrorBroadcastIsFinished
+	}
+
+	info.Status = status
+	info.UpdatedAt = time.Now()
+
+	return s.storage.SaveBroadcast(ctx, info)
+}
+
+func (s *BroadcastService) run(ctx context.Context, info *storage.BroadcastInfo, source RecipientSource, broadcast *runningBroadcast) {
+	log := logrus.WithField("broadcastID", info.ID)
+	limiter := rate.NewLimiter(s.telegramClient.GlobalRateLimit()*rate.Limit(s.cfg.RateShare), 1)
+	// blocked users are counted separately instead of being reported as delivered
+	sendCtx := client.WithIgnoredErrors(ctx)
+	processed := 0
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, info.ID)
+		stopStatus := broadcast.stopStatus
+		s.mu.Unlock()
+
+		if stopStatus != "" {
+			info.Status = stopStatus
+		}
+
+		// the stopped broadcast is released, so it can be resumed at once after restart
+		info.Owner = ""
+		info.HeartbeatAt = time.Time{}
+
+		s.saveProgress(context.WithoutCancel(ctx), info, log)
+		broadcast.cancel()
+		close(broadcast.done)
+
+		log.WithFields(logrus.Fields{
+			"status":    info.Status,
+			"delivered": info.Delivered,
+			"blocked":   info.Blocked,
+			"failed":    info.Failed,
+		}).Info("broadcast stopped")
+	}()
+
+	log.Info("start broadcast")
+	s.heartbeat(ctx, info, log)
+
+	for {
+		recipients, nextCursor, err := source.Next(ctx, info.Cursor, s.cfg.BatchSize)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			log.WithError(err).Error("failed to get broadcast recipients")
+			info.Status = storage.BroadcastStatusFailed
+			info.LastError = err.Error()
+			return
+		}
+
+		for ; info.Offset < len(recipients); info.Offset++ {
+			if err = s.deliver(sendCtx, limiter, info, recipients[info.Offset]); err != nil {
+				return
+			}
+
+			processed++
+
+			if processed%max(s.cfg.ProgressSaveEvery, 1) == 0 || time.Since(info.HeartbeatAt) >= s.cfg.LeaseTimeout/3 {
+				s.heartbeat(ctx, info, log)
+			}
+		}
+
+		if nextCursor == "" || len(recipients) == 0 {
+			info.Status = storage.BroadcastStatusCompleted
+			return
+		}
+
+		info.Cursor = nextCursor
+		info.Offset = 0
+		s.heartbeat(ctx, info, log)
+	}
+}
+
+// deliver sends the message to the recipient and updates counters. It returns an error only when
+// the broadcast is interrupted and the recipient has to be processed again.
+func (s *BroadcastService) deliver(ctx context.Context, limiter *rate.Limiter, info *storage.BroadcastInfo, recipient Recipient) error {
+	for {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		err := s.send(ctx, &info.Template, recipient)
+
+		if err == nil {
+			info.Delivered++
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var floodWait *client.FloodWaitError
+
+		if errors.As(err, &floodWait) {
+			if err = s.wait(ctx, info, floodWait.RetryAfter); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if errors.Is(err, domain.ErrorBotBlocked) || errors.Is(err, domain.ErrorUserDeactivated) {
+			info.Blocked++
+			return nil
+		}
+
+		logrus.WithError(err).WithField("chatID", recipient.ChatID).Warn("failed to deliver broadcast message")
+		info.Failed++
+		info.LastError = err.Error()
+
+		return nil
+	}
+}
+
+func (s *BroadcastService) send(ctx context.Context, template *storage.BroadcastTemplate, recipient Recipient) error {
+	text := template.Text
+
+	if template.LocaleKey != "" && s.locales != nil {
+		args := make([]any, 0, len(template.LocaleArgs))
+
+		for _, arg := range template.LocaleArgs {
+			args = append(args, arg)
+		}
+
+		text = s.locales.GetWithCulture(recipient.Lang, template.LocaleKey, args...)
+	}
+
+	keyboard := s.localizeKeyboard(template, recipient.Lang)
+
+	if template.MediaType == "" {
+		options := []client.MessageOptions{client.WithSendParseMode(template.ParseMode)}
+
+		if keyboard != nil {
+			options = append(options, client.WithSendInlineKeyboard(*keyboard))
+		}
+
+		_, err := s.telegramClient.SendMessage(ctx, recipient.ChatID, text, options...)
+
+		return err
+	}
+
+	options := []client.MediaOptions{
+		client.WithMediaCaption(text),
+		client.WithMediaParseMode(template.ParseMode),
+	}
+
+	if keyboard != nil {
+		options = append(options, client.WithMediaInlineKeyboard(*keyboard))
+	}
+
+	media := client.FileByID(template.MediaFileID)
+
+	var err error
+
+	switch template.MediaType {
+	case storage.BroadcastMediaPhoto:
+		_, _, err = s.telegramClient.SendPhoto(ctx, recipient.ChatID, media, options...)
+	case storage.BroadcastMediaVideo:
+		_, _, err = s.telegramClient.SendVideo(ctx, recipient.ChatID, media, options...)
+	case storage.BroadcastMediaAnimation:
+		_, _, err = s.telegramClient.SendAnimation(ctx, recipient.ChatID, media, options...)
+	case storage.BroadcastMediaDocument:
+		_, _, err = s.telegramClient.SendDocument(ctx, recipient.ChatID, media, options...)
+	case storage.BroadcastMediaAudio:
+		_, _, err = s.telegramClient.SendAudio(ctx, recipient.ChatID, media, options...)
+	case storage.BroadcastMediaVoice:
+		_, _, err = s.telegramClient.SendVoice(ctx, recipient.ChatID, media, options...)
+	default:
+		err = errors.New("unknown broadcast media type " + string(template.MediaType))
+	}
+
+	return err
+}
+
+func (s *BroadcastService) localizeKeyboard(template *storage.BroadcastTemplate, lang string) *models.InlineKeyboardMarkup {
+	if template.InlineKeyboard == nil || !template.LocalizeKeyboard || s.locales == nil {
+		return template.InlineKeyboard
+	}
+
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: make([][]models.InlineKeyboardButton, 0, len(template.InlineKeyboard.InlineKeyboard)),
+	}
+
+	for _, row := range template.InlineKeyboard.InlineKeyboard {
+		localizedRow := make([]models.InlineKeyboardButton, 0, len(row))
+
+		for _, button := range row {
+			button.Text = s.locales.GetWithCulture(lang, button.Text)
+			localizedRow = append(localizedRow, button)
+		}
+
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, localizedRow)
+	}
+
+	return keyboard
+}
+
+// wait sleeps for the flood wait of the broadcast, heartbeats keep the broadcast owned by the process meanwhile.
+func (s *BroadcastService) wait(ctx context.Context, info *storage.BroadcastInfo, delay time.Duration) error {
+	log := logrus.WithField("broadcastID", info.ID)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(s.cfg.LeaseTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+			s.heartbeat(ctx, info, log)
+		}
+	}
+}
+
+// heartbeat saves the progress of the broadcast owned by the process, Resume of other processes waits
+// for the lease timeout after the last heartbeat.
+func (s *BroadcastService) heartbeat(ctx context.Context, info *storage.BroadcastInfo, log *logrus.Entry) {
+	info.Owner = s.owner
+	info.HeartbeatAt = time.Now()
+	s.saveProgress(ctx, info, log)
+}
+
+func (s *BroadcastService) saveProgress(ctx context.Context, info *storage.BroadcastInfo, log *logrus.Entry) {
+	info.UpdatedAt = time.Now()
+
+	if err := s.storage.SaveBroadcast(ctx, info); err != nil {
+		log.WithError(err).Error("failed to save broadcast progress")
+	}
+}
+
+func randomOwnerID() string {
+	buf := make([]byte, 8)
+	_, _ = cryptorand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,260 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+)
+
+// fakeBotApi fails sendMessage to the chat with its queued failures before succeeding.
+type fakeBotApi struct {
+	mu       sync.Mutex
+	failures map[int64][]string
+	sent     []int64
+}
+
+func (f *fakeBotApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	response := map[string]any{"ok": true, "result": true}
+
+	switch path.Base(r.URL.Path) {
+	case "getMe":
+		response["result"] = models.User{ID: 1, IsBot: true}
+	case "sendMessage":
+		_ = r.ParseMultipartForm(1 << 20)
+		chatID, _ := strconv.ParseInt(r.FormValue("chat_id"), 10, 64)
+
+		f.mu.Lock()
+
+		if failures := f.failures[chatID]; len(failures) > 0 {
+			f.failures[chatID] = failures[1:]
+
+			switch failures[0] {
+			case "blocked":
+				response = map[string]any{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}
+			case "flood":
+				response = map[string]any{"ok": false, "error_code": 429, "description": "Too Many Requests", "parameters": map[string]any{"retry_after": 0}}
+			default:
+				response = map[string]any{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"}
+			}
+		} else {
+			f.sent = append(f.sent, chatID)
+			response["result"] = models.Message{ID: len(f.sent)}
+		}
+
+		f.mu.Unlock()
+	}
+
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeBotApi) sentChats() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.sent)
+}
+
+func newTestService(t *testing.T, failures map[int64][]string) (*BroadcastService, *fakeBotApi, *storage.InMemoryBroadcastStorage) {
+	api := &fakeBotApi{failures: failures}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	telegramClient, err := client.CreateTelegramClient(&config.TelegramConfig{
+		Token:                    "test",
+		TelegramApiUrl:           server.URL,
+		GlobalMessagesPerSecond:  -1,
+		PrivateMessagesPerMinute: -1,
+		RetryMaxAttempts:         -1,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broadcastStorage := storage.NewInMemoryBroadcastStorage()
+
+	return NewBroadcastService(config.BroadcastConfig{BatchSize: 2}, broadcastStorage, telegramClient, nil), api, broadcastStorage
+}
+
+// waitStopped waits until the broadcast launched by the service stops.
+func waitStopped(s *BroadcastService, broadcastID string) {
+	s.mu.Lock()
+	broadcast, ok := s.running[broadcastID]
+	s.mu.Unlock()
+
+	if ok {
+		<-broadcast.done
+	}
+}
+
+func recipients(chatIDs ...int64) SliceSource {
+	source := make(SliceSource, 0, len(chatIDs))
+
+	for _, chatID := range chatIDs {
+		source = append(source, Recipient{ChatID: chatID})
+	}
+
+	return source
+}
+
+func TestBroadcastCounters(t *testing.T) {
+	service, api, broadcastStorage := newTestService(t, map[int64][]string{
+		2: {"blocked"},
+		3: {"failed"},
+		4: {"flood", "flood"},
+	})
+
+	ctx := context.Background()
+
+	if err := service.Start(ctx, "id", storage.BroadcastTemplate{Text: "hello"}, recipients(1, 2, 3, 4)); err != nil {
+		t.Fatal(err)
+	}
+
+	waitStopped(service, "id")
+
+	info, _ := broadcastStorage.GetBroadcast(ctx, "id")
+
+	if info.Status != storage.BroadcastStatusCompleted || info.Delivered != 2 || info.Blocked != 1 || info.Failed != 1 {
+		t.Fatalf("expected completed broadcast with 2 delivered, 1 blocked and 1 failed, got %+v", info)
+	}
+
+	if sent := api.sentChats(); !slices.Equal(sent, []int64{1, 4}) {
+		t.Fatalf("expected flood waited recipient retried, got sent to %v", sent)
+	}
+}
+
+func TestBroadcastResumesFromCursor(t *testing.T) {
+	service, api, broadcastStorage := newTestService(t, nil)
+	ctx := context.Background()
+
+	_ = broadcastStorage.SaveBroadcast(ctx, &storage.BroadcastInfo{
+		ID:        "id",
+		Status:    storage.BroadcastStatusPaused,
+		Template:  storage.BroadcastTemplate{Text: "hello"},
+		Cursor:    "2",
+		Offset:    1,
+		Delivered: 3,
+	})
+
+	if err := service.Resume(ctx, "id", recipients(1, 2, 3, 4, 5)); err != nil {
+		t.Fatal(err)
+	}
+
+	waitStopped(service, "id")
+
+	if sent := api.sentChats(); !slices.Equal(sent, []int64{4, 5}) {
+		t.Fatalf("expected broadcast resumed after the saved offset of the page, got sent to %v", sent)
+	}
+
+	if info, _ := broadcastStorage.GetBroadcast(ctx, "id"); info.Status != storage.BroadcastStatusCompleted || info.Delivered != 5 {
+		t.Fatalf("expected completed broadcast with 5 delivered, got %+v", info)
+	}
+}
+
+func TestBroadcastPauseAndCancel(t *testing.T) {
+	service, _, broadcastStorage := newTestService(t, nil)
+	ctx := context.Background()
+
+	for _, status := range []storage.BroadcastStatus{storage.BroadcastStatusPaused, storage.BroadcastStatusCancelled} {
+		broadcastID := string(status)
+		reached := make(chan struct{})
+
+		// the second page blocks until the broadcast is stopped
+		source := RecipientSourceFunc(func(ctx context.Context, cursor string, limit int) ([]Recipient, string, error) {
+			if cursor == "" {
+				return []Recipient{{ChatID: 1}}, "next", nil
+			}
+
+			close(reached)
+			<-ctx.Done()
+
+			return nil, "", ctx.Err()
+		})
+
+		if err := service.Start(ctx, broadcastID, storage.BroadcastTemplate{Text: "hello"}, source); err != nil {
+			t.Fatal(err)
+		}
+
+		<-reached
+
+		var err error
+
+		if status == storage.BroadcastStatusPaused {
+			err = service.Pause(ctx, broadcastID)
+		} else {
+			err = service.Cancel(ctx, broadcastID)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, _ := broadcastStorage.GetBroadcast(ctx, broadcastID)
+
+		if info.Status != status || info.Cursor != "next" || info.Delivered != 1 || info.Owner != "" {
+			t.Fatalf("expected %s broadcast saved with its progress, got %+v", status, info)
+		}
+	}
+
+	if err := service.Resume(ctx, string(storage.BroadcastStatusCancelled), recipients(1)); !errors.Is(err, domain.ErrorBroadcastIsFinished) {
+		t.Fatalf("expected cancelled broadcast not resumed, got %v", err)
+	}
+}
+
+func TestResumeBroadcastOfAnotherProcess(t *testing.T) {
+	service, _, broadcastStorage := newTestService(t, nil)
+	ctx := context.Background()
+
+	info := &storage.BroadcastInfo{
+		ID:          "id",
+		Status:      storage.BroadcastStatusRunning,
+		Template:    storage.BroadcastTemplate{Text: "hello"},
+		Owner:       "other",
+		HeartbeatAt: time.Now(),
+	}
+
+	_ = broadcastStorage.SaveBroadcast(ctx, info)
+
+	if err := service.Resume(ctx, "id", recipients(1)); !errors.Is(err, domain.ErrorBroadcastIsRunning) {
+		t.Fatalf("expected broadcast with fresh heartbeat of another process not resumed, got %v", err)
+	}
+
+	info.HeartbeatAt = time.Now().Add(-2 * defaultLeaseTimeout)
+	_ = broadcastStorage.SaveBroadcast(ctx, info)
+
+	if err := service.Resume(ctx, "id", recipients(1)); err != nil {
+		t.Fatalf("expected broadcast with expired heartbeat resumed, got %v", err)
+	}
+
+	waitStopped(service, "id")
+}
+
+func TestConfigDefaults(t *testing.T) {
+	for _, share := range []float64{0, -1, 2} {
+		cfg := withDefaults(config.BroadcastConfig{RateShare: share})
+
+		if cfg.RateShare != defaultRateShare || cfg.BatchSize != defaultBatchSize || cfg.LeaseTimeout != defaultLeaseTimeout {
+			t.Fatalf("expected defaults for rate share %v, got %+v", share, cfg)
+		}
+	}
+
+	cfg := withDefaults(config.BroadcastConfig{RateShare: 0.2, BatchSize: 10})
+
+	if cfg.RateShare != 0.2 || cfg.BatchSize != 10 {
+		t.Fatalf("expected configured values kept, got %+v", cfg)
+	}
+}
@@ -0,0 +1,50 @@
+package broadcast
+
+import (
+	"context"
+	"strconv"
+)
+
+type Recipient struct {
+	ChatID int64
+	Lang   string
+}
+
+// RecipientSource pages through broadcast recipients. Cursor is empty for the first page and
+// an empty next cursor marks the last page. Pages must be stable, broadcast resumes from a saved cursor.
+type RecipientSource interface {
+	Next(ctx context.Context, cursor string, limit int) (recipients []Recipient, nextCursor string, err error)
+}
+
+type RecipientSourceFunc func(ctx context.Context, cursor string, limit int) ([]Recipient, string, error)
+
+func (f RecipientSourceFunc) Next(ctx context.Context, cursor string, limit int) ([]Recipient, string, error) {
+	return f(ctx, cursor, limit)
+}
+
+// SliceSource serves recipients from the in-memory slice.
+type SliceSource []Recipient
+
+func (s SliceSource) Next(_ context.Context, cursor string, limit int) ([]Recipient, string, error) {
+	start := 0
+
+	if cursor != "" {
+		var err error
+
+		if start, err = strconv.Atoi(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if start >= len(s) {
+		return nil, "", nil
+	}
+
+	end := min(start+limit, len(s))
+
+	if end == len(s) {
+		return s[start:end], "", nil
+	}
+
+	return s[start:end], strconv.Itoa(end), nil
+}
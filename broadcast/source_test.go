@@ -0,0 +1,40 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSliceSource(t *testing.T) {
+	source := SliceSource{{ChatID: 1}, {ChatID: 2}, {ChatID: 3}}
+
+	var (
+		cursor string
+		chats  []int64
+		pages  int
+	)
+
+	for {
+		recipients, next, err := source.Next(context.Background(), cursor, 2)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		pages++
+
+		for _, recipient := range recipients {
+			chats = append(chats, recipient.ChatID)
+		}
+
+		if next == "" {
+			break
+		}
+
+		cursor = next
+	}
+
+	if pages != 2 || len(chats) != 3 || chats[2] != 3 {
+		t.Fatalf("unexpected pages %d with chats %v", pages, chats)
+	}
+}
@@ -0,0 +1,468 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/limiter"
+)
+
+type AdminRight string
+
+const (
+	AdminRightRestrictMembers AdminRight = "can_restrict_members"
+	AdminRightPromoteMembers  AdminRight = "can_promote_members"
+	AdminRightChangeInfo      AdminRight = "can_change_info"
+	AdminRightInviteUsers     AdminRight = "can_invite_users"
+	AdminRightPinMessages     AdminRight = "can_pin_messages"
+	AdminRightEditMessages    AdminRight = "can_edit_messages"
+	AdminRightManageTopics    AdminRight = "can_manage_topics"
+	AdminRightDeleteMessages  AdminRight = "can_delete_messages"
+)
+
+// botMemberCacheTTL is how long the bot membership is reused for rights checks, changes reported by
+// my_chat_member updates are applied immediately.
+const botMemberCacheTTL = 30 * time.Second
+
+type InviteLinkOptions func(linkCfg *bot.CreateChatInviteLinkParams)
+
+type cachedBotMember struct {
+	member    *models.ChatMember
+	expiresAt time.Time
+}
+
+// botMemberCache keeps the bot membership per chat, so admin actions do not request it every time.
+type botMemberCache struct {
+	mu      sync.Mutex
+	members map[int64]cachedBotMember
+}
+
+func (c *botMemberCache) get(chatID int64) (*models.ChatMember, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.members[chatID]
+
+	if !ok || time.Now().After(cached.expiresAt) {
+		return nil, false
+	}
+
+	return cached.member, true
+}
+
+func (c *botMemberCache) set(chatID int64, member *models.ChatMember) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	if c.members == nil {
+		c.members = make(map[int64]cachedBotMember)
+	}
+
+	for id, cached := range c.members {
+		if now.After(cached.expiresAt) {
+			delete(c.members, id)
+		}
+	}
+
+	c.members[chatID] = cachedBotMember{member: member, expiresAt: now.Add(botMemberCacheTTL)}
+}
+
+// MissingRightsError is returned when the bot is not an administrator of the chat or lacks some of
+// the rights required by the action. It matches domain.ErrorNotEnoughRights.
+type MissingRightsError struct {
+	ChatID int64
+	Rights []AdminRight
+}
+
+func (e *MissingRightsError) Error() string {
+	rights := make([]string, 0, len(e.Rights))
+
+	for _, right := range e.Rights {
+		rights = append(rights, string(right))
+	}
+
+	return fmt.Sprintf("bot has no rights in chat %d: %s", e.ChatID, strings.Join(rights, ", "))
+}
+
+func (e *MissingRightsError) Is(target error) bool {
+	return target == domain.ErrorNotEnoughRights
+}
+
+func WithInviteLinkName(name string) InviteLinkOptions {
+	return func(linkCfg *bot.CreateChatInviteLinkParams) {
+		linkCfg.Name = name
+	}
+}
+
+func WithInviteLinkExpireDate(expireDate time.Time) InviteLinkOptions {
+	return func(linkCfg *bot.CreateChatInviteLinkParams) {
+		linkCfg.ExpireDate = int(expireDate.Unix())
+	}
+}
+
+// WithInviteLinkMemberLimit limits the number of users joined by the link, it can not be combined with join requests.
+func WithInviteLinkMemberLimit(limit int) InviteLinkOptions {
+	return func(linkCfg *bot.CreateChatInviteLinkParams) {
+		linkCfg.MemberLimit = limit
+	}
+}
+
+// WithInviteLinkJoinRequest makes users joining by the link send a join request, see ProcessChatJoinRequest.
+func WithInviteLinkJoinRequest() InviteLinkOptions {
+	return func(linkCfg *bot.CreateChatInviteLinkParams) {
+		linkCfg.CreatesJoinRequest = true
+	}
+}
+
+// missingRights returns the rights the chat member lacks, the owner has all rights.
+func missingRights(member *models.ChatMember, rights []AdminRight) []AdminRight {
+	if member.Type == models.ChatMemberTypeOwner {
+		return nil
+	}
+
+	if member.Type != models.ChatMemberTypeAdministrator || member.Administrator == nil {
+		return rights
+	}
+
+	admin := member.Administrator
+	granted := map[AdminRight]bool{
+		AdminRightRestrictMembers: admin.CanRestrictMembers,
+		AdminRightPromoteMembers:  admin.CanPromoteMembers,
+		AdminRightChangeInfo:      admin.CanChangeInfo,
+		AdminRightInviteUsers:     admin.CanInviteUsers,
+		AdminRightPinMessages:     admin.CanPinMessages,
+		AdminRightEditMessages:    admin.CanEditMessages,
+		AdminRightManageTopics:    admin.CanManageTopics,
+		AdminRightDeleteMessages:  admin.CanDeleteMessages,
+	}
+
+	var missing []AdminRight
+
+	for _, right := range rights {
+		if !granted[right] {
+			missing = append(missing, right)
+		}
+	}
+
+	return missing
+}
+
+// CheckBotRights returns MissingRightsError when the bot lacks any of the rights in the chat.
+func (t *TelegramClient) CheckBotRights(ctx context.Context, chatID int64, rights ...AdminRight) error {
+	member, err := t.botMember(ctx, chatID)
+
+	if err != nil {
+		return err
+	}
+
+	if missing := missingRights(member, rights); len(missing) > 0 {
+		return &MissingRightsError{ChatID: chatID, Rights: missing}
+	}
+
+	return nil
+}
+
+// botMember returns the membership of the bot in the chat, it is cached for botMemberCacheTTL.
+func (t *TelegramClient) botMember(ctx context.Context, chatID int64) (*models.ChatMember, error) {
+	if member, ok := t.botMembers.get(chatID); ok {
+		return member, nil
+	}
+
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	member, err := t.api.GetChatMember(ctx, &bot.GetChatMemberParams{
+		ChatID: chatID,
+		UserID: t.api.ID(),
+	})
+
+	if err != nil {
+		return nil, t.handleError(ctx, chatID, err)
+	}
+
+	t.botMembers.set(chatID, member)
+
+	return member, nil
+}
+
+// observeBotMember applies changes of the bot membership from incoming updates to the cache.
+func (t *TelegramClient) observeBotMember(update *models.Update) {
+	if update.MyChatMember != nil {
+		member := update.MyChatMember.NewChatMember
+		t.botMembers.set(update.MyChatMember.Chat.ID, &member)
+	}
+}
+
+// waitAdminLimits checks the bot rights and waits for the chat limits before the admin action.
+func (t *TelegramClient) waitAdminLimits(ctx context.Context, chatID int64, rights ...AdminRight) (context.Context, error) {
+	if len(rights) > 0 {
+		if err := t.CheckBotRights(ctx, chatID, rights...); err != nil {
+			return ctx, err
+		}
+	}
+
+	return t.waitChatLimits(ctx, chatID)
+}
+
+// RestrictChatMember replaces the permissions of the user in the supergroup until the date,
+// zero date restricts the user forever.
+func (t *TelegramClient) RestrictChatMember(ctx context.Context, chatID, userID int64, permissions models.ChatPermissions, until time.Time) error {
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightRestrictMembers)
+
+	if err != nil {
+		return err
+	}
+
+	cfg := &bot.RestrictChatMemberParams{
+		ChatID:                        chatID,
+		UserID:                        userID,
+		Permissions:                   &permissions,
+		UseIndependentChatPermissions: true,
+	}
+
+	if !until.IsZero() {
+		cfg.UntilDate = int(until.Unix())
+	}
+
+	_, err = t.api.RestrictChatMember(ctx, cfg)
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// MuteChatMember forbids the user to send messages for the duration, zero duration mutes forever.
+// Telegram treats durations shorter than 30 seconds or longer than 366 days as forever.
+func (t *TelegramClient) MuteChatMember(ctx context.Context, chatID, userID int64, duration time.Duration) error {
+	var until time.Time
+
+	if duration > 0 {
+		until = time.Now().Add(duration)
+	}
+
+	return t.RestrictChatMember(ctx, chatID, userID, models.ChatPermissions{}, until)
+}
+
+// UnmuteChatMember lifts the restrictions of the user, the chat default permissions apply again.
+func (t *TelegramClient) UnmuteChatMember(ctx context.Context, chatID, userID int64) error {
+	return t.RestrictChatMember(ctx, chatID, userID, models.ChatPermissions{
+		CanSendMessages:       true,
+		CanSendAudios:         true,
+		CanSendDocuments:      true,
+		CanSendPhotos:         true,
+		CanSendVideos:         true,
+		CanSendVideoNotes:     true,
+		CanSendVoiceNotes:     true,
+		CanSendPolls:          true,
+		CanSendOtherMessages:  true,
+		CanAddWebPagePreviews: true,
+		CanChangeInfo:         true,
+		CanInviteUsers:        true,
+		CanPinMessages:        true,
+		CanManageTopics:       true,
+		CanReactToMessages:    true,
+	}, time.Time{})
+}
+
+// PromoteChatMember grants the administrator rights to the user, the bot can grant only rights it holds itself.
+func (t *TelegramClient) PromoteChatMember(ctx context.Context, chatID, userID int64, rights models.ChatAdministratorRights) error {
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightPromoteMembers)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.PromoteChatMember(ctx, &bot.PromoteChatMemberParams{
+		ChatID:                  chatID,
+		UserID:                  userID,
+		IsAnonymous:             rights.IsAnonymous,
+		CanManageChat:           rights.CanManageChat,
+		CanDeleteMessages:       rights.CanDeleteMessages,
+		CanManageVideoChats:     rights.CanManageVideoChats,
+		CanRestrictMembers:      rights.CanRestrictMembers,
+		CanPromoteMembers:       rights.CanPromoteMembers,
+		CanChangeInfo:           rights.CanChangeInfo,
+		CanInviteUsers:          rights.CanInviteUsers,
+		CanPostMessages:         rights.CanPostMessages,
+		CanEditMessages:         rights.CanEditMessages,
+		CanPinMessages:          rights.CanPinMessages,
+		CanPostStories:          rights.CanPostStories,
+		CanEditStories:          rights.CanEditStories,
+		CanDeleteStories:        rights.CanDeleteStories,
+		CanManageTopics:         rights.CanManageTopics,
+		CanManageDirectMessages: rights.CanManageDirectMessages,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// DemoteChatMember revokes all administrator rights of the user promoted by the bot.
+func (t *TelegramClient) DemoteChatMember(ctx context.Context, chatID, userID int64) error {
+	return t.PromoteChatMember(ctx, chatID, userID, models.ChatAdministratorRights{})
+}
+
+// SetChatPermissions sets the default permissions of all members of the group.
+func (t *TelegramClient) SetChatPermissions(ctx context.Context, chatID int64, permissions models.ChatPermissions) error {
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightRestrictMembers)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.SetChatPermissions(ctx, &bot.SetChatPermissionsParams{
+		ChatID:                        chatID,
+		Permissions:                   permissions,
+		UseIndependentChatPermissions: true,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// waitPinLimits checks the right to pin messages before waiting for the chat limits. Groups require
+// can_pin_messages and channels can_edit_messages, channels are not always recognized by the chat id,
+// so any of the rights is accepted. Private chats require no rights.
+func (t *TelegramClient) waitPinLimits(ctx context.Context, chatID int64) (context.Context, error) {
+	if t.chatLimiter.ChatType(chatID) == limiter.ChatTypePrivate {
+		return t.waitChatLimits(ctx, chatID)
+	}
+
+	member, err := t.botMember(ctx, chatID)
+
+	if err != nil {
+		return ctx, err
+	}
+
+	if len(missingRights(member, []AdminRight{AdminRightPinMessages})) > 0 &&
+		len(missingRights(member, []AdminRight{AdminRightEditMessages})) > 0 {
+		return ctx, &MissingRightsError{ChatID: chatID, Rights: []AdminRight{AdminRightPinMessages, AdminRightEditMessages}}
+	}
+
+	return t.waitChatLimits(ctx, chatID)
+}
+
+func (t *TelegramClient) PinChatMessage(ctx context.Context, chatID int64, messageID int, silent bool) error {
+	ctx, err := t.waitPinLimits(ctx, chatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.PinChatMessage(ctx, &bot.PinChatMessageParams{
+		ChatID:              chatID,
+		MessageID:           messageID,
+		DisableNotification: silent,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// UnpinChatMessage unpins the message, zero message id unpins the most recent pinned message.
+func (t *TelegramClient) UnpinChatMessage(ctx context.Context, chatID int64, messageID int) error {
+	ctx, err := t.waitPinLimits(ctx, chatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.UnpinChatMessage(ctx, &bot.UnpinChatMessageParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+func (t *TelegramClient) UnpinAllChatMessages(ctx context.Context, chatID int64) error {
+	ctx, err := t.waitPinLimits(ctx, chatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.UnpinAllChatMessages(ctx, &bot.UnpinAllChatMessagesParams{ChatID: chatID})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+func (t *TelegramClient) SetChatTitle(ctx context.Context, chatID int64, title string) error {
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightChangeInfo)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.SetChatTitle(ctx, &bot.SetChatTitleParams{
+		ChatID: chatID,
+		Title:  title,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// SetChatPhoto uploads the new chat photo streamed from reader.
+func (t *TelegramClient) SetChatPhoto(ctx context.Context, chatID int64, fileName string, reader io.Reader) error {
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightChangeInfo)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.SetChatPhoto(ctx, &bot.SetChatPhotoParams{
+		ChatID: chatID,
+		Photo:  FileFromReader(fileName, reader),
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// CreateChatInviteLink creates the additional invite link of the chat, use WithInviteLinkJoinRequest
+// for links that require approval.
+func (t *TelegramClient) CreateChatInviteLink(ctx context.Context, chatID int64, options ...InviteLinkOptions) (*models.ChatInviteLink, error) {
+	cfg := &bot.CreateChatInviteLinkParams{ChatID: chatID}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightInviteUsers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := t.api.CreateChatInviteLink(ctx, cfg)
+
+	if err != nil {
+		return nil, t.handleError(ctx, chatID, err)
+	}
+
+	return link, nil
+}
+
+// RevokeChatInviteLink revokes the invite link created by the bot and returns the revoked link.
+func (t *TelegramClient) RevokeChatInviteLink(ctx context.Context, chatID int64, inviteLink string) (*models.ChatInviteLink, error) {
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightInviteUsers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := t.api.RevokeChatInviteLink(ctx, &bot.RevokeChatInviteLinkParams{
+		ChatID:     chatID,
+		InviteLink: inviteLink,
+	})
+
+	if err != nil {
+		return nil, t.handleError(ctx, chatID, err)
+	}
+
+	return link, nil
+}
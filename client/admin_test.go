@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+)
+
+func TestMissingRights(t *testing.T) {
+	rights := []AdminRight{AdminRightRestrictMembers, AdminRightPinMessages}
+
+	owner := &models.ChatMember{Type: models.ChatMemberTypeOwner, Owner: &models.ChatMemberOwner{}}
+
+	if missing := missingRights(owner, rights); len(missing) != 0 {
+		t.Fatalf("expected owner to have all rights, got %v", missing)
+	}
+
+	admin := &models.ChatMember{
+		Type:          models.ChatMemberTypeAdministrator,
+		Administrator: &models.ChatMemberAdministrator{CanRestrictMembers: true},
+	}
+
+	if missing := missingRights(admin, rights); !slices.Equal(missing, []AdminRight{AdminRightPinMessages}) {
+		t.Fatalf("expected missing pin right, got %v", missing)
+	}
+
+	member := &models.ChatMember{Type: models.ChatMemberTypeMember, Member: &models.ChatMemberMember{}}
+
+	if missing := missingRights(member, rights); !slices.Equal(missing, rights) {
+		t.Fatalf("expected member to miss all rights, got %v", missing)
+	}
+
+	err := error(&MissingRightsError{ChatID: -100, Rights: rights})
+
+	if !errors.Is(err, domain.ErrorNotEnoughRights) {
+		t.Fatalf("expected not enough rights error, got %v", err)
+	}
+}
+
+func TestPinRightsCheck(t *testing.T) {
+	var calls []string
+
+	client, _ := newTestClient(t, func(method string, _ *http.Request) any {
+		calls = append(calls, method)
+
+		if method == "getChatMember" {
+			// administrator of the channel the bot has not seen updates from yet
+			return map[string]any{"status": "administrator", "user": map[string]any{"id": 1}, "can_edit_messages": true}
+		}
+
+		return true
+	})
+
+	ctx := t.Context()
+
+	if err := client.PinChatMessage(ctx, -100, 1, true); err != nil {
+		t.Fatalf("expected pin allowed by the channel right, got %v", err)
+	}
+
+	if err := client.UnpinChatMessage(ctx, -100, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.PinChatMessage(ctx, 42, 1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"getChatMember", "pinChatMessage", "unpinChatMessage", "pinChatMessage"}
+
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("expected cached member and no check in private chat, got %v", calls)
+	}
+
+	client.observeBotMember(&models.Update{MyChatMember: &models.ChatMemberUpdated{
+		Chat:          models.Chat{ID: -100},
+		NewChatMember: models.ChatMember{Type: models.ChatMemberTypeMember, Member: &models.ChatMemberMember{}},
+	}})
+
+	if err := client.PinChatMessage(ctx, -100, 1, true); !errors.Is(err, domain.ErrorNotEnoughRights) {
+		t.Fatalf("expected demoted bot to miss rights, got %v", err)
+	}
+}
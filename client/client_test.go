@@ -0,0 +1,56 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+)
+
+// newTestClient creates the client calling the fake bot api server, the handler returns the result of the api
+// method or raw bytes of the downloaded file.
+func newTestClient(t *testing.T, handler func(method string, r *http.Request) any) (*TelegramClient, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := path.Base(r.URL.Path)
+
+		var result any = true
+
+		if method == "getMe" {
+			result = models.User{ID: 1, IsBot: true, Username: "test_bot"}
+		} else if handler != nil {
+			result = handler(method, r)
+		}
+
+		if data, ok := result.([]byte); ok {
+			_, _ = w.Write(data)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+	}))
+
+	t.Cleanup(server.Close)
+
+	client, err := CreateTelegramClient(&config.TelegramConfig{
+		Token:                    "test",
+		TelegramApiUrl:           server.URL,
+		GlobalMessagesPerSecond:  -1,
+		PrivateMessagesPerMinute: -1,
+		GroupMessagesPerMinute:   -1,
+		ChannelMessagesPerMinute: -1,
+		RetryMaxAttempts:         -1,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, server
+}
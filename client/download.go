@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTelegramApiUrl = "https://api.telegram.org"
+	mimeSniffLength       = 512
+)
+
+type DownloadFileStat struct {
+	FileName string
+	MimoType string
+	FileSize int64
+}
+
+type downloadParams struct {
+	maxSize int64
+}
+
+type DownloadOptions func(downloadCfg *downloadParams)
+
+// WithDownloadMaxSize overrides the configured maximum file size, non-positive value disables the check.
+func WithDownloadMaxSize(maxSize int64) DownloadOptions {
+	return func(downloadCfg *downloadParams) {
+		downloadCfg.maxSize = maxSize
+	}
+}
+
+// DownloadFileTo streams the file into dst without buffering it in memory.
+// It fails with domain.ErrorFileTooLarge once the file exceeds the maximum size.
+func (t *TelegramClient) DownloadFileTo(ctx context.Context, fileID string, dst io.Writer, options ...DownloadOptions) (*DownloadFileStat, error) {
+	cfg := &downloadParams{maxSize: t.maxDownloadSize}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	file, err := t.api.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+
+	if err != nil {
+		return nil, t.handleError(ctx, 0, err)
+	}
+
+	if cfg.maxSize > 0 && file.FileSize > cfg.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", domain.ErrorFileTooLarge, file.FileSize)
+	}
+
+	src, err := t.openFile(ctx, file.FilePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(src io.ReadCloser) {
+		err := src.Close()
+		if err != nil {
+			logrus.WithError(err).Error("failed to close file reader")
+		}
+	}(src)
+
+	var reader io.Reader = src
+
+	if cfg.maxSize > 0 {
+		reader = io.LimitReader(src, cfg.maxSize+1)
+	}
+
+	sniffer := &mimeSniffer{}
+
+	written, err := io.Copy(io.MultiWriter(dst, sniffer), reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.maxSize > 0 && written > cfg.maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", domain.ErrorFileTooLarge, cfg.maxSize)
+	}
+
+	return &DownloadFileStat{
+		FileName: filepath.Base(file.FilePath),
+		MimoType: http.DetectContentType(sniffer.head),
+		FileSize: written,
+	}, nil
+}
+
+func (t *TelegramClient) openFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	// local bot api server started with --local returns absolute paths on its own file system
+	if filepath.IsAbs(filePath) {
+		if file, err := os.Open(filePath); err == nil {
+			return file, nil
+		}
+	}
+
+	apiUrl := strings.TrimSuffix(t.apiUrl, "/")
+
+	if apiUrl == "" {
+		apiUrl = defaultTelegramApiUrl
+	}
+
+	fileUrl := fmt.Sprintf("%s/file/bot%s/%s", apiUrl, t.api.Token(), strings.TrimPrefix(filePath, "/"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileUrl, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.httpClient.Do(req)
+
+	if err != nil {
+		var urlErr *url.Error
+
+		if errors.As(err, &urlErr) {
+			urlErr.URL = strings.ReplaceAll(urlErr.URL, t.api.Token(), "***")
+		}
+
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to download file, status code %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+type mimeSniffer struct {
+	head []byte
+}
+
+func (m *mimeSniffer) Write(p []byte) (int, error) {
+	if rest := mimeSniffLength - len(m.head); rest > 0 {
+		m.head = append(m.head, p[:min(rest, len(p))]...)
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newDownloadTestClient(t *testing.T, reportedSize int64, content []byte) *TelegramClient {
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		if method == "getFile" {
+			return models.File{FileID: "file-id", FilePath: "photos/file_1.png", FileSize: reportedSize}
+		}
+
+		return content
+	})
+
+	return client
+}
+
+func TestDownloadFileTo(t *testing.T) {
+	content := append(pngHeader, bytes.Repeat([]byte{0}, 100)...)
+	client := newDownloadTestClient(t, int64(len(content)), content)
+
+	var dst bytes.Buffer
+
+	stat, err := client.DownloadFileTo(context.Background(), "file-id", &dst)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.FileName != "file_1.png" || stat.MimoType != "image/png" || stat.FileSize != int64(len(content)) {
+		t.Fatalf("unexpected file stat %+v", stat)
+	}
+
+	if !bytes.Equal(dst.Bytes(), content) {
+		t.Fatal("expected downloaded content to match")
+	}
+}
+
+func TestDownloadFileToSizeLimit(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 100)
+
+	// telegram reports the size of the file, it is checked before downloading
+	client := newDownloadTestClient(t, int64(len(content)), content)
+
+	if _, err := client.DownloadFileTo(context.Background(), "file-id", &bytes.Buffer{}, WithDownloadMaxSize(50)); !errors.Is(err, domain.ErrorFileTooLarge) {
+		t.Fatalf("expected file too large by reported size, got %v", err)
+	}
+
+	// without the reported size the stream is cut after the limit
+	client = newDownloadTestClient(t, 0, content)
+
+	if _, err := client.DownloadFileTo(context.Background(), "file-id", &bytes.Buffer{}, WithDownloadMaxSize(50)); !errors.Is(err, domain.ErrorFileTooLarge) {
+		t.Fatalf("expected file too large by streamed size, got %v", err)
+	}
+
+	if _, err := client.DownloadFileTo(context.Background(), "file-id", &bytes.Buffer{}, WithDownloadMaxSize(0)); err != nil {
+		t.Fatalf("expected disabled limit to download the file, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type EditCaptionOptions func(captionCfg *bot.EditMessageCaptionParams)
+type EditMediaOptions func(mediaCfg *bot.EditMessageMediaParams)
+type LiveLocationOptions func(locationCfg *LiveLocationParams)
+
+// LiveLocationParams holds the settings shared by sending and editing of live locations.
+type LiveLocationParams struct {
+	HorizontalAccuracy   float64
+	Heading              int
+	ProximityAlertRadius int
+	// LivePeriod replaces the period of the live location on edit, see bot api for allowed values.
+	LivePeriod  int
+	ReplyMarkup models.ReplyMarkup
+	// MessageThreadID is used on sending only, edits address the message by its id.
+	MessageThreadID int
+}
+
+func WithEditCaption(caption string) EditCaptionOptions {
+	return func(captionCfg *bot.EditMessageCaptionParams) {
+		captionCfg.Caption = caption
+	}
+}
+
+func WithEditCaptionParseMode(parseMode models.ParseMode) EditCaptionOptions {
+	return func(captionCfg *bot.EditMessageCaptionParams) {
+		captionCfg.ParseMode = parseMode
+	}
+}
+
+func WithEditCaptionEntities(entities []models.MessageEntity) EditCaptionOptions {
+	return func(captionCfg *bot.EditMessageCaptionParams) {
+		captionCfg.CaptionEntities = entities
+	}
+}
+
+func WithEditCaptionInlineKeyboard(keyboard models.InlineKeyboardMarkup) EditCaptionOptions {
+	return func(captionCfg *bot.EditMessageCaptionParams) {
+		captionCfg.ReplyMarkup = keyboard
+	}
+}
+
+func WithEditCaptionHTML() EditCaptionOptions {
+	return WithEditCaptionParseMode(models.ParseModeHTML)
+}
+
+func WithEditCaptionMarkdownV2() EditCaptionOptions {
+	return WithEditCaptionParseMode(models.ParseModeMarkdown)
+}
+
+func WithEditMediaInlineKeyboard(keyboard models.InlineKeyboardMarkup) EditMediaOptions {
+	return func(mediaCfg *bot.EditMessageMediaParams) {
+		mediaCfg.ReplyMarkup = keyboard
+	}
+}
+
+func WithLiveLocationAccuracy(meters float64) LiveLocationOptions {
+	return func(locationCfg *LiveLocationParams) {
+		locationCfg.HorizontalAccuracy = meters
+	}
+}
+
+func WithLiveLocationHeading(degrees int) LiveLocationOptions {
+	return func(locationCfg *LiveLocationParams) {
+		locationCfg.Heading = degrees
+	}
+}
+
+func WithLiveLocationProximityAlert(meters int) LiveLocationOptions {
+	return func(locationCfg *LiveLocationParams) {
+		locationCfg.ProximityAlertRadius = meters
+	}
+}
+
+func WithLiveLocationPeriod(seconds int) LiveLocationOptions {
+	return func(locationCfg *LiveLocationParams) {
+		locationCfg.LivePeriod = seconds
+	}
+}
+
+func WithLiveLocationThread(threadID int) LiveLocationOptions {
+	return func(locationCfg *LiveLocationParams) {
+		locationCfg.MessageThreadID = threadID
+	}
+}
+
+func WithLiveLocationInlineKeyboard(keyboard models.InlineKeyboardMarkup) LiveLocationOptions {
+	return func(locationCfg *LiveLocationParams) {
+		locationCfg.ReplyMarkup = keyboard
+	}
+}
+
+func newLiveLocationParams(options []LiveLocationOptions) *LiveLocationParams {
+	cfg := &LiveLocationParams{}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// EditMessageCaption edits the caption of the media message, the caption is removed when it is not set.
+func (t *TelegramClient) EditMessageCaption(ctx context.Context, recipientChatID int64, messageID int, options ...EditCaptionOptions) error {
+	cfg := &bot.EditMessageCaptionParams{
+		ChatID:    recipientChatID,
+		MessageID: messageID,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.EditMessageCaption(ctx, cfg)
+
+	return t.handleError(ctx, recipientChatID, err)
+}
+
+// EditMessageMedia replaces the media of the message, the new caption is set on the media,
+// see NewInputMediaPhoto and friends. Without the keyboard option the inline keyboard is removed.
+func (t *TelegramClient) EditMessageMedia(ctx context.Context, recipientChatID int64, messageID int, media models.InputMedia, options ...EditMediaOptions) error {
+	cfg := &bot.EditMessageMediaParams{
+		ChatID:    recipientChatID,
+		MessageID: messageID,
+		Media:     media,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.EditMessageMedia(ctx, cfg)
+
+	return t.handleError(ctx, recipientChatID, err)
+}
+
+// SendLiveLocation sends the location updated with EditMessageLiveLocation during the live period in seconds.
+func (t *TelegramClient) SendLiveLocation(ctx context.Context, recipientChatID int64, latitude, longitude float64, livePeriod int, options ...LiveLocationOptions) (int, error) {
+	cfg := newLiveLocationParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := t.api.SendLocation(ctx, &bot.SendLocationParams{
+		ChatID:               recipientChatID,
+		MessageThreadID:      cfg.MessageThreadID,
+		Latitude:             latitude,
+		Longitude:            longitude,
+		HorizontalAccuracy:   cfg.HorizontalAccuracy,
+		LivePeriod:           livePeriod,
+		Heading:              cfg.Heading,
+		ProximityAlertRadius: cfg.ProximityAlertRadius,
+		ReplyMarkup:          cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, t.handleError(ctx, recipientChatID, err)
+	}
+
+	return response.ID, nil
+}
+
+func (t *TelegramClient) EditMessageLiveLocation(ctx context.Context, recipientChatID int64, messageID int, latitude, longitude float64, options ...LiveLocationOptions) error {
+	cfg := newLiveLocationParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.EditMessageLiveLocation(ctx, &bot.EditMessageLiveLocationParams{
+		ChatID:               recipientChatID,
+		MessageID:            messageID,
+		Latitude:             latitude,
+		Longitude:            longitude,
+		LivePeriod:           cfg.LivePeriod,
+		HorizontalAccuracy:   cfg.HorizontalAccuracy,
+		Heading:              cfg.Heading,
+		ProximityAlertRadius: cfg.ProximityAlertRadius,
+		ReplyMarkup:          cfg.ReplyMarkup,
+	})
+
+	return t.handleError(ctx, recipientChatID, err)
+}
+
+// StopMessageLiveLocation stops updating of the live location before the live period expires.
+func (t *TelegramClient) StopMessageLiveLocation(ctx context.Context, recipientChatID int64, messageID int, options ...LiveLocationOptions) error {
+	cfg := newLiveLocationParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.StopMessageLiveLocation(ctx, &bot.StopMessageLiveLocationParams{
+		ChatID:      recipientChatID,
+		MessageID:   messageID,
+		ReplyMarkup: cfg.ReplyMarkup,
+	})
+
+	return t.handleError(ctx, recipientChatID, err)
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestEditMessageCaption(t *testing.T) {
+	var form map[string]string
+
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		_ = r.ParseMultipartForm(1 << 20)
+		form = map[string]string{
+			"method":     method,
+			"caption":    r.FormValue("caption"),
+			"parse_mode": r.FormValue("parse_mode"),
+			"message_id": r.FormValue("message_id"),
+		}
+
+		return models.Message{ID: 7}
+	})
+
+	if err := client.EditMessageCaption(context.Background(), 1, 7, WithEditCaption("<b>new</b>"), WithEditCaptionHTML()); err != nil {
+		t.Fatal(err)
+	}
+
+	if form["method"] != "editMessageCaption" || form["caption"] != "<b>new</b>" || form["parse_mode"] != "HTML" || form["message_id"] != "7" {
+		t.Fatalf("unexpected edit request %v", form)
+	}
+}
+
+func TestEditMessageMediaUpload(t *testing.T) {
+	var media map[string]any
+	var uploaded bool
+
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		_ = r.ParseMultipartForm(1 << 20)
+		_ = json.Unmarshal([]byte(r.FormValue("media")), &media)
+
+		field := strings.TrimPrefix(media["media"].(string), "attach://")
+		_, uploaded = r.MultipartForm.File[field]
+
+		return models.Message{ID: 7}
+	})
+
+	err := client.EditMessageMedia(context.Background(), 1, 7,
+		NewInputMediaPhoto(FileFromReader("photo.jpg", strings.NewReader("photo")), WithMediaCaption("caption")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !uploaded || media["caption"] != "caption" || media["type"] != "photo" {
+		t.Fatalf("expected uploaded photo with caption, got %v", media)
+	}
+}
+
+func TestLiveLocation(t *testing.T) {
+	var methods []string
+	var livePeriod string
+
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		_ = r.ParseMultipartForm(1 << 20)
+		methods = append(methods, method)
+
+		if method == "editMessageLiveLocation" {
+			livePeriod = r.FormValue("live_period")
+		}
+
+		return models.Message{ID: 3}
+	})
+
+	ctx := context.Background()
+
+	messageID, err := client.SendLiveLocation(ctx, 1, 55.75, 37.61, 600)
+
+	if err != nil || messageID != 3 {
+		t.Fatalf("expected live location message, got %d, %v", messageID, err)
+	}
+
+	_ = client.EditMessageLiveLocation(ctx, 1, messageID, 55.76, 37.62, WithLiveLocationPeriod(900))
+	_ = client.StopMessageLiveLocation(ctx, 1, messageID)
+
+	expected := []string{"sendLocation", "editMessageLiveLocation", "stopMessageLiveLocation"}
+
+	if strings.Join(methods, ",") != strings.Join(expected, ",") || livePeriod != "900" {
+		t.Fatalf("unexpected live location calls %v, period %s", methods, livePeriod)
+	}
+}
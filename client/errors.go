@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+)
+
+// BlockedHandlerFunc is called when the chat can not receive messages anymore: the user blocked the bot
+// or the account was deactivated.
+type BlockedHandlerFunc func(ctx context.Context, chatID int64, err error)
+
+// FloodWaitError is returned when telegram asks to wait before repeating the request.
+// It matches domain.ErrorFloodWait and wraps the original api error.
+type FloodWaitError struct {
+	RetryAfter time.Duration
+	err        error
+}
+
+func (e *FloodWaitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *FloodWaitError) Is(target error) bool {
+	return target == domain.ErrorFloodWait
+}
+
+func (e *FloodWaitError) Unwrap() error {
+	return e.err
+}
+
+// apiError matches one of the domain errors while keeping the original error available for errors.Is
+// checks against bot package errors.
+type apiError struct {
+	kind error
+	err  error
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+func (e *apiError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+var apiErrorDescriptions = []struct {
+	base        error
+	description string
+	kind        error
+}{
+	{bot.ErrorForbidden, "bot was blocked by the user", domain.ErrorBotBlocked},
+	{bot.ErrorForbidden, "user is deactivated", domain.ErrorUserDeactivated},
+	{bot.ErrorForbidden, "not enough rights", domain.ErrorNotEnoughRights},
+	{bot.ErrorForbidden, "have no rights", domain.ErrorNotEnoughRights},
+	{bot.ErrorBadRequest, "chat not found", domain.ErrorChatNotFound},
+	{bot.ErrorBadRequest, "message is not modified", domain.ErrorMessageNotModified},
+	{bot.ErrorBadRequest, "message to edit not found", domain.ErrorMessageToEditNotFound},
+	{bot.ErrorBadRequest, "not enough rights", domain.ErrorNotEnoughRights},
+	{bot.ErrorBadRequest, "need administrator rights", domain.ErrorNotEnoughRights},
+	{bot.ErrorBadRequest, "have no rights", domain.ErrorNotEnoughRights},
+}
+
+func classifyError(err error) error {
+	var tooManyRequests *bot.TooManyRequestsError
+
+	if errors.As(err, &tooManyRequests) {
+		return &FloodWaitError{
+			RetryAfter: time.Duration(tooManyRequests.RetryAfter) * time.Second,
+			err:        err,
+		}
+	}
+
+	msg := err.Error()
+
+	for _, description := range apiErrorDescriptions {
+		if errors.Is(err, description.base) && strings.Contains(msg, description.description) {
+			return &apiError{kind: description.kind, err: err}
+		}
+	}
+
+	return err
+}
+
+type ignoredErrorsCtxKey struct{}
+
+// WithIgnoredErrors overrides the errors ignored by the client for calls done with the returned context.
+// Called without errors it makes the calls report every error.
+func WithIgnoredErrors(ctx context.Context, errs ...error) context.Context {
+	return context.WithValue(ctx, ignoredErrorsCtxKey{}, errs)
+}
+
+// SetIgnoredErrors replaces the errors the client methods return as nil. By default the client ignores
+// domain.ErrorBotBlocked and domain.ErrorUserDeactivated.
+func (t *TelegramClient) SetIgnoredErrors(errs ...error) *TelegramClient {
+	t.ignoredErrors = errs
+
+	return t
+}
+
+// RegisterBlockedHandler sets the handler called when the chat blocked the bot, regardless of whether
+// the error is ignored.
+func (t *TelegramClient) RegisterBlockedHandler(handler BlockedHandlerFunc) *TelegramClient {
+	t.blockedHandler = handler
+
+	return t
+}
+
+func (t *TelegramClient) handleError(ctx context.Context, chatID int64, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	err = classifyError(err)
+
+	var floodWait *FloodWaitError
+
+	if chatID != 0 && errors.As(err, &floodWait) {
+		t.chatLimiter.Backoff(chatID, floodWait.RetryAfter)
+	}
+
+	if t.blockedHandler != nil && chatID != 0 &&
+		(errors.Is(err, domain.ErrorBotBlocked) || errors.Is(err, domain.ErrorUserDeactivated)) {
+		t.blockedHandler(ctx, chatID, err)
+	}
+
+	ignoredErrors := t.ignoredErrors
+
+	if ctxIgnoredErrors, ok := ctx.Value(ignoredErrorsCtxKey{}).([]error); ok {
+		ignoredErrors = ctxIgnoredErrors
+	}
+
+	if slices.ContainsFunc(ignoredErrors, func(target error) bool { return errors.Is(err, target) }) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/limiter"
+)
+
+func TestHandleError(t *testing.T) {
+	var blockedChatID int64
+
+	client := &TelegramClient{
+		chatLimiter:   limiter.NewChatLimiter(map[limiter.ChatType]limiter.LimitProfile{limiter.ChatTypePrivate: {Rate: 1, Burst: 1}}),
+		ignoredErrors: []error{domain.ErrorBotBlocked},
+	}
+	client.RegisterBlockedHandler(func(_ context.Context, chatID int64, _ error) {
+		blockedChatID = chatID
+	})
+
+	blocked := fmt.Errorf("%w, %s", bot.ErrorForbidden, "Forbidden: bot was blocked by the user")
+
+	if err := client.handleError(context.Background(), 42, blocked); err != nil {
+		t.Fatalf("expected ignored error, got %v", err)
+	}
+
+	if blockedChatID != 42 {
+		t.Fatalf("expected blocked handler for chat 42, got %d", blockedChatID)
+	}
+
+	err := client.handleError(WithIgnoredErrors(context.Background()), 42, blocked)
+
+	if !errors.Is(err, domain.ErrorBotBlocked) || !errors.Is(err, bot.ErrorForbidden) {
+		t.Fatalf("expected blocked error, got %v", err)
+	}
+
+	notModified := fmt.Errorf("%w, %s", bot.ErrorBadRequest, "Bad Request: message is not modified")
+
+	if err = client.handleError(context.Background(), 42, notModified); !errors.Is(err, domain.ErrorMessageNotModified) {
+		t.Fatalf("expected message not modified error, got %v", err)
+	}
+
+	err = client.handleError(context.Background(), 42, &bot.TooManyRequestsError{Message: "too many requests", RetryAfter: 3})
+
+	var floodWait *FloodWaitError
+
+	if !errors.As(err, &floodWait) || floodWait.RetryAfter != 3*time.Second || !errors.Is(err, domain.ErrorFloodWait) {
+		t.Fatalf("expected flood wait error, got %v", err)
+	}
+}
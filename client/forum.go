@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type ForumTopicOptions func(topicCfg *bot.CreateForumTopicParams)
+
+// WithForumTopicIconColor sets the icon color, see bot api for the allowed RGB values.
+func WithForumTopicIconColor(color int) ForumTopicOptions {
+	return func(topicCfg *bot.CreateForumTopicParams) {
+		topicCfg.IconColor = color
+	}
+}
+
+func WithForumTopicIconEmoji(customEmojiID string) ForumTopicOptions {
+	return func(topicCfg *bot.CreateForumTopicParams) {
+		topicCfg.IconCustomEmojiID = customEmojiID
+	}
+}
+
+// CreateForumTopic creates the topic in the forum supergroup, its message thread id is used to send
+// messages to the topic.
+func (t *TelegramClient) CreateForumTopic(ctx context.Context, chatID int64, name string, options ...ForumTopicOptions) (*models.ForumTopic, error) {
+	cfg := &bot.CreateForumTopicParams{
+		ChatID: chatID,
+		Name:   name,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightManageTopics)
+
+	if err != nil {
+		return nil, err
+	}
+
+	topic, err := t.api.CreateForumTopic(ctx, cfg)
+
+	if err != nil {
+		return nil, t.handleError(ctx, chatID, err)
+	}
+
+	return topic, nil
+}
+
+// CloseForumTopic closes the topic. The bot needs can_manage_topics unless it created the topic, so the
+// rights are left to telegram to check.
+func (t *TelegramClient) CloseForumTopic(ctx context.Context, chatID int64, threadID int) error {
+	ctx, err := t.waitChatLimits(ctx, chatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.CloseForumTopic(ctx, &bot.CloseForumTopicParams{
+		ChatID:          chatID,
+		MessageThreadID: threadID,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// ReopenForumTopic reopens the closed topic, the rights are checked like in CloseForumTopic.
+func (t *TelegramClient) ReopenForumTopic(ctx context.Context, chatID int64, threadID int) error {
+	ctx, err := t.waitChatLimits(ctx, chatID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.ReopenForumTopic(ctx, &bot.ReopenForumTopicParams{
+		ChatID:          chatID,
+		MessageThreadID: threadID,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// DeleteForumTopic deletes the topic together with all its messages, the bot needs can_delete_messages.
+func (t *TelegramClient) DeleteForumTopic(ctx context.Context, chatID int64, threadID int) error {
+	ctx, err := t.waitAdminLimits(ctx, chatID, AdminRightDeleteMessages)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = t.api.DeleteForumTopic(ctx, &bot.DeleteForumTopicParams{
+		ChatID:          chatID,
+		MessageThreadID: threadID,
+	})
+
+	return t.handleError(ctx, chatID, err)
+}
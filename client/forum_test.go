@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+)
+
+func TestForumTopicRights(t *testing.T) {
+	var calls []string
+
+	client, _ := newTestClient(t, func(method string, _ *http.Request) any {
+		calls = append(calls, method)
+
+		if method == "getChatMember" {
+			// the bot manages topics, but can not delete messages
+			return map[string]any{"status": "administrator", "user": map[string]any{"id": 1}, "can_manage_topics": true}
+		}
+
+		return true
+	})
+
+	ctx := t.Context()
+
+	if err := client.CloseForumTopic(ctx, -100, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.ReopenForumTopic(ctx, -100, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	var rightsErr *MissingRightsError
+
+	if err := client.DeleteForumTopic(ctx, -100, 5); !errors.Is(err, domain.ErrorNotEnoughRights) ||
+		!errors.As(err, &rightsErr) || !slices.Equal(rightsErr.Rights, []AdminRight{AdminRightDeleteMessages}) {
+		t.Fatalf("expected missing delete messages right, got %v", err)
+	}
+
+	if expected := []string{"closeForumTopic", "reopenForumTopic", "getChatMember"}; !slices.Equal(calls, expected) {
+		t.Fatalf("expected close and reopen left to telegram, got %v", calls)
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type InlineQueryOptions func(cfg *bot.AnswerInlineQueryParams)
+
+// WithInlineCacheTime sets how long in seconds telegram caches the results on its side.
+func WithInlineCacheTime(seconds int) InlineQueryOptions {
+	return func(cfg *bot.AnswerInlineQueryParams) {
+		cfg.CacheTime = seconds
+	}
+}
+
+// WithInlinePersonal makes telegram cache the results only for the user who sent the query.
+func WithInlinePersonal() InlineQueryOptions {
+	return func(cfg *bot.AnswerInlineQueryParams) {
+		cfg.IsPersonal = true
+	}
+}
+
+func WithInlineNextOffset(offset string) InlineQueryOptions {
+	return func(cfg *bot.AnswerInlineQueryParams) {
+		cfg.NextOffset = offset
+	}
+}
+
+// WithInlineStartButton shows the button above the results switching the user to the private chat with the bot.
+func WithInlineStartButton(text, startParameter string) InlineQueryOptions {
+	return func(cfg *bot.AnswerInlineQueryParams) {
+		cfg.Button = &models.InlineQueryResultsButton{
+			Text:           text,
+			StartParameter: startParameter,
+		}
+	}
+}
+
+func WithInlineWebAppButton(text, url string) InlineQueryOptions {
+	return func(cfg *bot.AnswerInlineQueryParams) {
+		cfg.Button = &models.InlineQueryResultsButton{
+			Text:   text,
+			WebApp: &models.WebAppInfo{URL: url},
+		}
+	}
+}
+
+func (t *TelegramClient) AnswerInlineQuery(ctx context.Context, inlineQueryID string, results []models.InlineQueryResult, options ...InlineQueryOptions) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	cfg := &bot.AnswerInlineQueryParams{
+		InlineQueryID: inlineQueryID,
+		Results:       results,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	_, err := t.api.AnswerInlineQuery(ctx, cfg)
+
+	return t.handleError(ctx, 0, err)
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/limiter"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultGlobalMessagesPerSecond  = 25
+	defaultPrivateMessagesPerMinute = 60
+	defaultPrivateMessagesBurst     = 2
+	defaultGroupMessagesPerMinute   = 20
+	defaultGroupMessagesBurst       = 3
+)
+
+type chatIDCtxKey struct{}
+
+func newChatLimiter(cfg *config.TelegramConfig) *limiter.ChatLimiter {
+	return limiter.NewChatLimiter(map[limiter.ChatType]limiter.LimitProfile{
+		limiter.ChatTypePrivate: limitProfile(cfg.PrivateMessagesPerMinute, cfg.PrivateMessagesBurst, defaultPrivateMessagesPerMinute, defaultPrivateMessagesBurst),
+		limiter.ChatTypeGroup:   limitProfile(cfg.GroupMessagesPerMinute, cfg.GroupMessagesBurst, defaultGroupMessagesPerMinute, defaultGroupMessagesBurst),
+		limiter.ChatTypeChannel: limitProfile(cfg.ChannelMessagesPerMinute, cfg.ChannelMessagesBurst, defaultGroupMessagesPerMinute, defaultGroupMessagesBurst),
+	})
+}
+
+func newGlobalLimiter(cfg *config.TelegramConfig) *rate.Limiter {
+	perSecond := cfg.GlobalMessagesPerSecond
+
+	if perSecond < 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+
+	if perSecond == 0 {
+		perSecond = defaultGlobalMessagesPerSecond
+	}
+
+	return rate.NewLimiter(rate.Limit(perSecond), perSecond)
+}
+
+// limitProfile converts the configured limit, zero values fall back to defaults and negative rate disables the limit.
+func limitProfile(perMinute, burst, defaultPerMinute, defaultBurst int) limiter.LimitProfile {
+	if perMinute < 0 {
+		return limiter.LimitProfile{Rate: -1}
+	}
+
+	if perMinute == 0 {
+		perMinute = defaultPerMinute
+	}
+
+	if burst <= 0 {
+		burst = defaultBurst
+	}
+
+	return limiter.LimitProfile{
+		Rate:  rate.Every(time.Minute / time.Duration(perMinute)),
+		Burst: burst,
+	}
+}
+
+// waitChatLimits waits for the global and chat limits. The returned context carries the chat id,
+// so flood wait received by the transport pauses the chat.
+func (t *TelegramClient) waitChatLimits(ctx context.Context, chatID int64) (context.Context, error) {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return ctx, err
+	}
+
+	t.chatLimiter.Wait(ctx, chatID)
+
+	return context.WithValue(ctx, chatIDCtxKey{}, chatID), nil
+}
+
+func (t *TelegramClient) onFloodWait(ctx context.Context, retryAfter time.Duration) {
+	if chatID, ok := ctx.Value(chatIDCtxKey{}).(int64); ok {
+		t.chatLimiter.Backoff(chatID, retryAfter)
+	}
+}
+
+// observeChatType remembers chat types from incoming updates, channels can not be recognized by the chat id.
+func (t *TelegramClient) observeChatType(update *models.Update) {
+	var chat *models.Chat
+
+	switch {
+	case update.ChannelPost != nil:
+		chat = &update.ChannelPost.Chat
+	case update.EditedChannelPost != nil:
+		chat = &update.EditedChannelPost.Chat
+	case update.MyChatMember != nil:
+		chat = &update.MyChatMember.Chat
+	case update.Message != nil:
+		chat = &update.Message.Chat
+	}
+
+	if chat == nil {
+		return
+	}
+
+	switch chat.Type {
+	case models.ChatTypeChannel:
+		t.chatLimiter.SetChatType(chat.ID, limiter.ChatTypeChannel)
+	case models.ChatTypeGroup, models.ChatTypeSupergroup:
+		t.chatLimiter.SetChatType(chat.ID, limiter.ChatTypeGroup)
+	}
+}
+
+func (t *TelegramClient) ChatLimiter() *limiter.ChatLimiter {
+	return t.chatLimiter
+}
@@ -0,0 +1,426 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// MediaParams holds the settings shared by every media sending method.
+type MediaParams struct {
+	Caption             string
+	ParseMode           models.ParseMode
+	CaptionEntities     []models.MessageEntity
+	ReplyMarkup         models.ReplyMarkup
+	HasSpoiler          bool
+	DisableNotification bool
+	ProtectContent      bool
+	SupportsStreaming   bool
+	MessageThreadID     int
+	Thumbnail           models.InputFile
+	Progress            ProgressFunc
+}
+
+type MediaOptions func(mediaCfg *MediaParams)
+type MediaGroupOptions func(groupCfg *bot.SendMediaGroupParams)
+
+func WithMediaCaption(caption string) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.Caption = caption
+	}
+}
+
+func WithMediaParseMode(parseMode models.ParseMode) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.ParseMode = parseMode
+	}
+}
+
+func WithMediaCaptionEntities(entities []models.MessageEntity) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.CaptionEntities = entities
+	}
+}
+
+func WithMediaInlineKeyboard(keyboard models.InlineKeyboardMarkup) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.ReplyMarkup = keyboard
+	}
+}
+
+func WithMediaReplyKeyboard(keyboard models.ReplyKeyboardMarkup) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.ReplyMarkup = keyboard
+	}
+}
+
+func WithMediaSpoiler() MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.HasSpoiler = true
+	}
+}
+
+func WithMediaDisableNotification() MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.DisableNotification = true
+	}
+}
+
+func WithMediaProtectContent() MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.ProtectContent = true
+	}
+}
+
+// WithMediaStreaming marks the video as suitable for streaming, it is ignored by other media types.
+func WithMediaStreaming() MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.SupportsStreaming = true
+	}
+}
+
+// WithMediaThread sends the media to the forum topic.
+func WithMediaThread(threadID int) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.MessageThreadID = threadID
+	}
+}
+
+func WithMediaGroupThread(threadID int) MediaGroupOptions {
+	return func(groupCfg *bot.SendMediaGroupParams) {
+		groupCfg.MessageThreadID = threadID
+	}
+}
+
+func WithMediaGroupDisableNotification() MediaGroupOptions {
+	return func(groupCfg *bot.SendMediaGroupParams) {
+		groupCfg.DisableNotification = true
+	}
+}
+
+func WithMediaGroupProtectContent() MediaGroupOptions {
+	return func(groupCfg *bot.SendMediaGroupParams) {
+		groupCfg.ProtectContent = true
+	}
+}
+
+// FileByID references a file already stored on telegram servers.
+func FileByID(fileID string) models.InputFile {
+	return &models.InputFileString{Data: fileID}
+}
+
+// FileByURL lets telegram download the file by itself.
+func FileByURL(fileURL string) models.InputFile {
+	return &models.InputFileString{Data: fileURL}
+}
+
+func newMediaParams(options []MediaOptions) *MediaParams {
+	cfg := &MediaParams{}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// SendPhoto sends a photo and returns the message id together with the file id of the largest photo size.
+func (t *TelegramClient) SendPhoto(ctx context.Context, recipientChatID int64, photo models.InputFile, options ...MediaOptions) (int, string, error) {
+	cfg := newMediaParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	response, err := t.api.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID:              recipientChatID,
+		MessageThreadID:     cfg.MessageThreadID,
+		Photo:               cfg.trackProgress(photo),
+		Caption:             cfg.Caption,
+		ParseMode:           cfg.ParseMode,
+		CaptionEntities:     cfg.CaptionEntities,
+		HasSpoiler:          cfg.HasSpoiler,
+		DisableNotification: cfg.DisableNotification,
+		ProtectContent:      cfg.ProtectContent,
+		ReplyMarkup:         cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, "", t.handleError(ctx, recipientChatID, err)
+	}
+
+	return response.ID, messageFileID(response), nil
+}
+
+func (t *TelegramClient) SendVideo(ctx context.Context, recipientChatID int64, video models.InputFile, options ...MediaOptions) (int, string, error) {
+	cfg := newMediaParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	response, err := t.api.SendVideo(ctx, &bot.SendVideoParams{
+		ChatID:              recipientChatID,
+		MessageThreadID:     cfg.MessageThreadID,
+		Video:               cfg.trackProgress(video),
+		Thumbnail:           cfg.Thumbnail,
+		Caption:             cfg.Caption,
+		ParseMode:           cfg.ParseMode,
+		CaptionEntities:     cfg.CaptionEntities,
+		HasSpoiler:          cfg.HasSpoiler,
+		SupportsStreaming:   cfg.SupportsStreaming,
+		DisableNotification: cfg.DisableNotification,
+		ProtectContent:      cfg.ProtectContent,
+		ReplyMarkup:         cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, "", t.handleError(ctx, recipientChatID, err)
+	}
+
+	return response.ID, messageFileID(response), nil
+}
+
+func (t *TelegramClient) SendAudio(ctx context.Context, recipientChatID int64, audio models.InputFile, options ...MediaOptions) (int, string, error) {
+	cfg := newMediaParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	response, err := t.api.SendAudio(ctx, &bot.SendAudioParams{
+		ChatID:              recipientChatID,
+		MessageThreadID:     cfg.MessageThreadID,
+		Audio:               cfg.trackProgress(audio),
+		Thumbnail:           cfg.Thumbnail,
+		Caption:             cfg.Caption,
+		ParseMode:           cfg.ParseMode,
+		CaptionEntities:     cfg.CaptionEntities,
+		DisableNotification: cfg.DisableNotification,
+		ProtectContent:      cfg.ProtectContent,
+		ReplyMarkup:         cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, "", t.handleError(ctx, recipientChatID, err)
+	}
+
+	return response.ID, messageFileID(response), nil
+}
+
+func (t *TelegramClient) SendVoice(ctx context.Context, recipientChatID int64, voice models.InputFile, options ...MediaOptions) (int, string, error) {
+	cfg := newMediaParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	response, err := t.api.SendVoice(ctx, &bot.SendVoiceParams{
+		ChatID:              recipientChatID,
+		MessageThreadID:     cfg.MessageThreadID,
+		Voice:               cfg.trackProgress(voice),
+		Caption:             cfg.Caption,
+		ParseMode:           cfg.ParseMode,
+		CaptionEntities:     cfg.CaptionEntities,
+		DisableNotification: cfg.DisableNotification,
+		ProtectContent:      cfg.ProtectContent,
+		ReplyMarkup:         cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, "", t.handleError(ctx, recipientChatID, err)
+	}
+
+	return response.ID, messageFileID(response), nil
+}
+
+func (t *TelegramClient) SendAnimation(ctx context.Context, recipientChatID int64, animation models.InputFile, options ...MediaOptions) (int, string, error) {
+	cfg := newMediaParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	response, err := t.api.SendAnimation(ctx, &bot.SendAnimationParams{
+		ChatID:              recipientChatID,
+		MessageThreadID:     cfg.MessageThreadID,
+		Animation:           cfg.trackProgress(animation),
+		Thumbnail:           cfg.Thumbnail,
+		Caption:             cfg.Caption,
+		ParseMode:           cfg.ParseMode,
+		CaptionEntities:     cfg.CaptionEntities,
+		HasSpoiler:          cfg.HasSpoiler,
+		DisableNotification: cfg.DisableNotification,
+		ProtectContent:      cfg.ProtectContent,
+		ReplyMarkup:         cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, "", t.handleError(ctx, recipientChatID, err)
+	}
+
+	return response.ID, messageFileID(response), nil
+}
+
+func (t *TelegramClient) SendDocument(ctx context.Context, recipientChatID int64, document models.InputFile, options ...MediaOptions) (int, string, error) {
+	cfg := newMediaParams(options)
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	response, err := t.api.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:              recipientChatID,
+		MessageThreadID:     cfg.MessageThreadID,
+		Document:            cfg.trackProgress(document),
+		Thumbnail:           cfg.Thumbnail,
+		Caption:             cfg.Caption,
+		ParseMode:           cfg.ParseMode,
+		CaptionEntities:     cfg.CaptionEntities,
+		DisableNotification: cfg.DisableNotification,
+		ProtectContent:      cfg.ProtectContent,
+		ReplyMarkup:         cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, "", t.handleError(ctx, recipientChatID, err)
+	}
+
+	return response.ID, messageFileID(response), nil
+}
+
+// SendMediaGroup sends an album of 2-10 items. Captions are set per item, see NewInputMediaPhoto and friends.
+func (t *TelegramClient) SendMediaGroup(ctx context.Context, recipientChatID int64, media []models.InputMedia, options ...MediaGroupOptions) ([]int, error) {
+	cfg := &bot.SendMediaGroupParams{
+		ChatID: recipientChatID,
+		Media:  media,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.api.SendMediaGroup(ctx, cfg)
+
+	if err != nil {
+		return nil, t.handleError(ctx, recipientChatID, err)
+	}
+
+	messageIDs := make([]int, 0, len(response))
+
+	for _, message := range response {
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	return messageIDs, nil
+}
+
+func NewInputMediaPhoto(photo models.InputFile, options ...MediaOptions) models.InputMedia {
+	cfg := newMediaParams(options)
+	media, attachment := inputMediaSource(photo)
+
+	return &models.InputMediaPhoto{
+		Media:           media,
+		Caption:         cfg.Caption,
+		ParseMode:       cfg.ParseMode,
+		CaptionEntities: cfg.CaptionEntities,
+		HasSpoiler:      cfg.HasSpoiler,
+		MediaAttachment: attachment,
+	}
+}
+
+func NewInputMediaVideo(video models.InputFile, options ...MediaOptions) models.InputMedia {
+	cfg := newMediaParams(options)
+	media, attachment := inputMediaSource(video)
+
+	return &models.InputMediaVideo{
+		Media:             media,
+		Caption:           cfg.Caption,
+		ParseMode:         cfg.ParseMode,
+		CaptionEntities:   cfg.CaptionEntities,
+		HasSpoiler:        cfg.HasSpoiler,
+		SupportsStreaming: cfg.SupportsStreaming,
+		MediaAttachment:   attachment,
+	}
+}
+
+func NewInputMediaAudio(audio models.InputFile, options ...MediaOptions) models.InputMedia {
+	cfg := newMediaParams(options)
+	media, attachment := inputMediaSource(audio)
+
+	return &models.InputMediaAudio{
+		Media:           media,
+		Caption:         cfg.Caption,
+		ParseMode:       cfg.ParseMode,
+		CaptionEntities: cfg.CaptionEntities,
+		MediaAttachment: attachment,
+	}
+}
+
+func NewInputMediaDocument(document models.InputFile, options ...MediaOptions) models.InputMedia {
+	cfg := newMediaParams(options)
+	media, attachment := inputMediaSource(document)
+
+	return &models.InputMediaDocument{
+		Media:           media,
+		Caption:         cfg.Caption,
+		ParseMode:       cfg.ParseMode,
+		CaptionEntities: cfg.CaptionEntities,
+		MediaAttachment: attachment,
+	}
+}
+
+// attachCounter makes attach names of uploaded album items unique, items may share the file name.
+var attachCounter atomic.Uint64
+
+func inputMediaSource(file models.InputFile) (string, io.Reader) {
+	switch f := file.(type) {
+	case *models.InputFileUpload:
+		return fmt.Sprintf("attach://file%d_%s", attachCounter.Add(1), f.Filename), f.Data
+	case *models.InputFileString:
+		return f.Data, nil
+	}
+
+	return "", nil
+}
+
+func messageFileID(message *models.Message) string {
+	switch {
+	case len(message.Photo) > 0:
+		return message.Photo[len(message.Photo)-1].FileID
+	case message.Video != nil:
+		return message.Video.FileID
+	case message.Animation != nil:
+		return message.Animation.FileID
+	case message.Audio != nil:
+		return message.Audio.FileID
+	case message.Voice != nil:
+		return message.Voice.FileID
+	case message.Document != nil:
+		return message.Document.FileID
+	}
+
+	return ""
+}
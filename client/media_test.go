@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestSendMediaGroupUniqueAttachments(t *testing.T) {
+	uploads := make(map[string]string)
+	var media []map[string]any
+
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse upload: %v", err)
+			return nil
+		}
+
+		for field, files := range r.MultipartForm.File {
+			file, _ := files[0].Open()
+			data, _ := io.ReadAll(file)
+			uploads[field] = string(data)
+		}
+
+		_ = json.Unmarshal([]byte(r.FormValue("media")), &media)
+
+		return []models.Message{{ID: 10}, {ID: 11}}
+	})
+
+	messageIDs, err := client.SendMediaGroup(context.Background(), 1, []models.InputMedia{
+		NewInputMediaPhoto(&models.InputFileUpload{Filename: "photo.jpg", Data: strings.NewReader("first")}),
+		NewInputMediaPhoto(&models.InputFileUpload{Filename: "photo.jpg", Data: strings.NewReader("second")}),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messageIDs) != 2 || len(uploads) != 2 || len(media) != 2 {
+		t.Fatalf("expected two uploaded items, got %v, %v", messageIDs, uploads)
+	}
+
+	for i, expected := range []string{"first", "second"} {
+		field := strings.TrimPrefix(media[i]["media"].(string), "attach://")
+
+		if uploads[field] != expected {
+			t.Fatalf("expected item %d to upload %s, got %s", i, expected, uploads[field])
+		}
+	}
+}
+
+func TestSendVideoStreaming(t *testing.T) {
+	var streaming []string
+
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		_ = r.ParseMultipartForm(1 << 20)
+		streaming = append(streaming, r.FormValue("supports_streaming"))
+
+		return models.Message{ID: 1, Video: &models.Video{FileID: "video-id"}}
+	})
+
+	_, fileID, err := client.SendVideo(context.Background(), 1, FileByID("video-id"))
+
+	if err != nil || fileID != "video-id" {
+		t.Fatalf("expected video file id, got %s, %v", fileID, err)
+	}
+
+	_, _, _ = client.SendVideo(context.Background(), 1, FileByID("video-id"), WithMediaStreaming())
+
+	if len(streaming) != 2 || streaming[0] == "true" || streaming[1] != "true" {
+		t.Fatalf("expected streaming only with the option, got %v", streaming)
+	}
+}
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+)
+
+// CurrencyStars is the currency of payments in Telegram Stars, such invoices are paid without a provider.
+const CurrencyStars = "XTR"
+
+type Invoice struct {
+	Title       string
+	Description string
+	// Payload is not shown to the user and is returned in pre-checkout query and successful payment.
+	Payload  string
+	Currency string
+	Prices   []models.LabeledPrice
+}
+
+// NewStarsInvoice creates the invoice for the amount of Telegram Stars.
+func NewStarsInvoice(title, description, payload string, amount int) Invoice {
+	return Invoice{
+		Title:       title,
+		Description: description,
+		Payload:     payload,
+		Currency:    CurrencyStars,
+		Prices:      []models.LabeledPrice{{Label: title, Amount: amount}},
+	}
+}
+
+type InvoiceParams struct {
+	ProviderToken       string
+	ProviderData        string
+	MaxTipAmount        int
+	SuggestedTipAmounts []int
+	PhotoURL            string
+	NeedName            bool
+	NeedPhoneNumber     bool
+	NeedEmail           bool
+	NeedShippingAddress bool
+	IsFlexible          bool
+	SubscriptionPeriod  time.Duration
+	ReplyMarkup         models.ReplyMarkup
+}
+
+type InvoiceOptions func(invoiceCfg *InvoiceParams)
+
+// WithInvoiceProviderToken overrides the provider token from config.
+func WithInvoiceProviderToken(token string) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.ProviderToken = token
+	}
+}
+
+func WithInvoiceProviderData(data string) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.ProviderData = data
+	}
+}
+
+func WithInvoiceTips(maxTipAmount int, suggestedTipAmounts ...int) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.MaxTipAmount = maxTipAmount
+		invoiceCfg.SuggestedTipAmounts = suggestedTipAmounts
+	}
+}
+
+func WithInvoicePhoto(url string) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.PhotoURL = url
+	}
+}
+
+func WithInvoiceContacts(needName, needPhoneNumber, needEmail bool) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.NeedName = needName
+		invoiceCfg.NeedPhoneNumber = needPhoneNumber
+		invoiceCfg.NeedEmail = needEmail
+	}
+}
+
+// WithInvoiceShipping requests the shipping address, flexible invoices receive shipping queries to calculate the price.
+func WithInvoiceShipping(flexible bool) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.NeedShippingAddress = true
+		invoiceCfg.IsFlexible = flexible
+	}
+}
+
+// WithInvoiceSubscription makes the invoice link a Stars subscription, telegram supports only 30 days period.
+func WithInvoiceSubscription(period time.Duration) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.SubscriptionPeriod = period
+	}
+}
+
+func WithInvoiceInlineKeyboard(keyboard models.InlineKeyboardMarkup) InvoiceOptions {
+	return func(invoiceCfg *InvoiceParams) {
+		invoiceCfg.ReplyMarkup = keyboard
+	}
+}
+
+func (t *TelegramClient) newInvoiceParams(invoice Invoice, options []InvoiceOptions) (*InvoiceParams, error) {
+	cfg := &InvoiceParams{}
+
+	if invoice.Currency != CurrencyStars {
+		cfg.ProviderToken = t.paymentProviderToken
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	if len(invoice.Prices) == 0 {
+		return nil, fmt.Errorf("%w: prices are empty", domain.ErrorInvalidInvoice)
+	}
+
+	if invoice.Currency == CurrencyStars {
+		if len(invoice.Prices) != 1 {
+			return nil, fmt.Errorf("%w: stars invoice must contain exactly one price", domain.ErrorInvalidInvoice)
+		}
+
+		if cfg.ProviderToken != "" || cfg.MaxTipAmount > 0 || cfg.NeedShippingAddress {
+			return nil, fmt.Errorf("%w: stars invoice does not support provider, tips and shipping", domain.ErrorInvalidInvoice)
+		}
+
+		return cfg, nil
+	}
+
+	if cfg.ProviderToken == "" {
+		return nil, fmt.Errorf("%w: provider token is required for %s", domain.ErrorInvalidInvoice, invoice.Currency)
+	}
+
+	if cfg.SubscriptionPeriod > 0 {
+		return nil, fmt.Errorf("%w: subscriptions are supported only for stars", domain.ErrorInvalidInvoice)
+	}
+
+	return cfg, nil
+}
+
+// SendInvoice sends the invoice message and returns its id.
+func (t *TelegramClient) SendInvoice(ctx context.Context, recipientChatID int64, invoice Invoice, options ...InvoiceOptions) (int, error) {
+	cfg, err := t.newInvoiceParams(invoice, options)
+
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, err = t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	msg, err := t.api.SendInvoice(ctx, &bot.SendInvoiceParams{
+		ChatID:              recipientChatID,
+		Title:               invoice.Title,
+		Description:         invoice.Description,
+		Payload:             invoice.Payload,
+		ProviderToken:       cfg.ProviderToken,
+		Currency:            invoice.Currency,
+		Prices:              invoice.Prices,
+		MaxTipAmount:        cfg.MaxTipAmount,
+		SuggestedTipAmounts: cfg.SuggestedTipAmounts,
+		ProviderData:        cfg.ProviderData,
+		PhotoURL:            cfg.PhotoURL,
+		NeedName:            cfg.NeedName,
+		NeedPhoneNumber:     cfg.NeedPhoneNumber,
+		NeedEmail:           cfg.NeedEmail,
+		NeedShippingAddress: cfg.NeedShippingAddress,
+		IsFlexible:          cfg.IsFlexible,
+		ReplyMarkup:         cfg.ReplyMarkup,
+	})
+
+	if err != nil {
+		return 0, t.handleError(ctx, recipientChatID, err)
+	}
+
+	return msg.ID, nil
+}
+
+// CreateInvoiceLink creates the link paying the invoice, e.g. to open it from a web app.
+func (t *TelegramClient) CreateInvoiceLink(ctx context.Context, invoice Invoice, options ...InvoiceOptions) (string, error) {
+	cfg, err := t.newInvoiceParams(invoice, options)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = t.globalLimiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	link, err := t.api.CreateInvoiceLink(ctx, &bot.CreateInvoiceLinkParams{
+		Title:               invoice.Title,
+		Description:         invoice.Description,
+		Payload:             invoice.Payload,
+		ProviderToken:       cfg.ProviderToken,
+		Currency:            invoice.Currency,
+		Prices:              invoice.Prices,
+		SubscriptionPeriod:  int(cfg.SubscriptionPeriod.Seconds()),
+		MaxTipAmount:        cfg.MaxTipAmount,
+		SuggestedTipAmounts: cfg.SuggestedTipAmounts,
+		ProviderData:        cfg.ProviderData,
+		PhotoURL:            cfg.PhotoURL,
+		NeedName:            cfg.NeedName,
+		NeedPhoneNumber:     cfg.NeedPhoneNumber,
+		NeedEmail:           cfg.NeedEmail,
+		NeedShippingAddress: cfg.NeedShippingAddress,
+		IsFlexible:          cfg.IsFlexible,
+	})
+
+	if err != nil {
+		return "", t.handleError(ctx, 0, err)
+	}
+
+	return link, nil
+}
+
+// AnswerShippingQuery answers the shipping query, empty error message accepts the address with the shipping options.
+func (t *TelegramClient) AnswerShippingQuery(ctx context.Context, shippingQueryID string, shippingOptions []models.ShippingOption, errorMessage string) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.AnswerShippingQuery(ctx, &bot.AnswerShippingQueryParams{
+		ShippingQueryID: shippingQueryID,
+		OK:              errorMessage == "",
+		ShippingOptions: shippingOptions,
+		ErrorMessage:    errorMessage,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
+
+// AnswerPreCheckoutQuery confirms the payment, non-empty error message rejects it.
+func (t *TelegramClient) AnswerPreCheckoutQuery(ctx context.Context, preCheckoutQueryID string, errorMessage string) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.AnswerPreCheckoutQuery(ctx, &bot.AnswerPreCheckoutQueryParams{
+		PreCheckoutQueryID: preCheckoutQueryID,
+		OK:                 errorMessage == "",
+		ErrorMessage:       errorMessage,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
+
+func (t *TelegramClient) RefundStarPayment(ctx context.Context, userID int64, telegramPaymentChargeID string) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.RefundStarPayment(ctx, &bot.RefundStarPaymentParams{
+		UserID:                  userID,
+		TelegramPaymentChargeID: telegramPaymentChargeID,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
@@ -0,0 +1,327 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+// BotProfile declares the bot profile applied by SyncBotProfile.
+type BotProfile struct {
+	// Locales contains name and descriptions by language code, empty code is the default for all users.
+	Locales  map[string]BotProfileLocale
+	Commands []ScopedCommands
+	// MenuButton is the default menu button of private chats, nil keeps the current one.
+	MenuButton models.InputMenuButton
+}
+
+// BotProfileLocale contains the name and descriptions of the language, empty fields keep the current values.
+type BotProfileLocale struct {
+	Name             string
+	Description      string
+	ShortDescription string
+}
+
+// ScopedCommands is the command list shown to users of the scope with the language, nil scope is the default scope.
+type ScopedCommands struct {
+	Scope        models.BotCommandScope
+	LanguageCode string
+	Commands     []models.BotCommand
+}
+
+func MenuButtonCommands() models.InputMenuButton {
+	return &models.MenuButtonCommands{Type: models.MenuButtonTypeCommands}
+}
+
+func MenuButtonWebApp(text, webAppURL string) models.InputMenuButton {
+	return &models.MenuButtonWebApp{
+		Type:   models.MenuButtonTypeWebApp,
+		Text:   text,
+		WebApp: models.WebAppInfo{URL: webAppURL},
+	}
+}
+
+func MenuButtonDefault() models.InputMenuButton {
+	return &models.MenuButtonDefault{Type: models.MenuButtonTypeDefault}
+}
+
+func (t *TelegramClient) GetScopedBotCommands(ctx context.Context, scope models.BotCommandScope, languageCode string) ([]models.BotCommand, error) {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	commands, err := t.api.GetMyCommands(ctx, &bot.GetMyCommandsParams{
+		Scope:        scope,
+		LanguageCode: languageCode,
+	})
+
+	if err != nil {
+		return nil, t.handleError(ctx, 0, err)
+	}
+
+	return commands, nil
+}
+
+// SetScopedBotCommands sets the commands of the scope, e.g. models.BotCommandScopeAllGroupChats, for users
+// with the language. Empty language code sets the commands for users without dedicated commands.
+func (t *TelegramClient) SetScopedBotCommands(ctx context.Context, scope models.BotCommandScope, languageCode string, commands []models.BotCommand) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.SetMyCommands(ctx, &bot.SetMyCommandsParams{
+		Commands:     commands,
+		Scope:        scope,
+		LanguageCode: languageCode,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
+
+// DeleteScopedBotCommands deletes the commands of the scope, users see the commands of the wider scope.
+func (t *TelegramClient) DeleteScopedBotCommands(ctx context.Context, scope models.BotCommandScope, languageCode string) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.DeleteMyCommands(ctx, &bot.DeleteMyCommandsParams{
+		Scope:        scope,
+		LanguageCode: languageCode,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
+
+func (t *TelegramClient) GetBotName(ctx context.Context, languageCode string) (string, error) {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	name, err := t.api.GetMyName(ctx, &bot.GetMyNameParams{LanguageCode: languageCode})
+
+	if err != nil {
+		return "", t.handleError(ctx, 0, err)
+	}
+
+	return name.Name, nil
+}
+
+// SetBotName sets the name for users with the language, empty name removes the name of the language.
+func (t *TelegramClient) SetBotName(ctx context.Context, languageCode, name string) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.SetMyName(ctx, &bot.SetMyNameParams{
+		Name:         name,
+		LanguageCode: languageCode,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
+
+func (t *TelegramClient) GetBotDescription(ctx context.Context, languageCode string) (string, error) {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	description, err := t.api.GetMyDescription(ctx, &bot.GetMyDescriptionParams{LanguageCode: languageCode})
+
+	if err != nil {
+		return "", t.handleError(ctx, 0, err)
+	}
+
+	return description.Description, nil
+}
+
+// SetBotDescription sets the description shown in the empty chat with the bot.
+func (t *TelegramClient) SetBotDescription(ctx context.Context, languageCode, description string) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.SetMyDescription(ctx, &bot.SetMyDescriptionParams{
+		Description:  description,
+		LanguageCode: languageCode,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
+
+func (t *TelegramClient) GetBotShortDescription(ctx context.Context, languageCode string) (string, error) {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	description, err := t.api.GetMyShortDescription(ctx, &bot.GetMyShortDescriptionParams{LanguageCode: languageCode})
+
+	if err != nil {
+		return "", t.handleError(ctx, 0, err)
+	}
+
+	return description.ShortDescription, nil
+}
+
+// SetBotShortDescription sets the description shown on the bot profile page and in shared links.
+func (t *TelegramClient) SetBotShortDescription(ctx context.Context, languageCode, shortDescription string) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.api.SetMyShortDescription(ctx, &bot.SetMyShortDescriptionParams{
+		ShortDescription: shortDescription,
+		LanguageCode:     languageCode,
+	})
+
+	return t.handleError(ctx, 0, err)
+}
+
+// SetChatMenuButton sets the menu button of the private chat, zero chat id sets the default menu button.
+func (t *TelegramClient) SetChatMenuButton(ctx context.Context, chatID int64, button models.InputMenuButton) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	cfg := &bot.SetChatMenuButtonParams{MenuButton: button}
+
+	if chatID != 0 {
+		cfg.ChatID = chatID
+	}
+
+	_, err := t.api.SetChatMenuButton(ctx, cfg)
+
+	return t.handleError(ctx, chatID, err)
+}
+
+// GetChatMenuButton returns the menu button of the private chat, zero chat id returns the default menu button.
+func (t *TelegramClient) GetChatMenuButton(ctx context.Context, chatID int64) (*models.MenuButton, error) {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	cfg := &bot.GetChatMenuButtonParams{}
+
+	if chatID != 0 {
+		cfg.ChatID = chatID
+	}
+
+	button, err := t.api.GetChatMenuButton(ctx, cfg)
+
+	if err != nil {
+		return nil, t.handleError(ctx, chatID, err)
+	}
+
+	return &button, nil
+}
+
+// SyncBotProfile applies the profile on startup. Only values differing from the current ones are set,
+// as telegram strictly limits changes of the name and descriptions.
+func (t *TelegramClient) SyncBotProfile(ctx context.Context, profile *BotProfile) error {
+	for languageCode, profileLocale := range profile.Locales {
+		if err := t.syncBotLocale(ctx, languageCode, profileLocale); err != nil {
+			return err
+		}
+	}
+
+	for _, scoped := range profile.Commands {
+		current, err := t.GetScopedBotCommands(ctx, scoped.Scope, scoped.LanguageCode)
+
+		if err != nil {
+			return err
+		}
+
+		if slices.Equal(current, scoped.Commands) {
+			continue
+		}
+
+		if len(scoped.Commands) == 0 {
+			err = t.DeleteScopedBotCommands(ctx, scoped.Scope, scoped.LanguageCode)
+		} else {
+			err = t.SetScopedBotCommands(ctx, scoped.Scope, scoped.LanguageCode, scoped.Commands)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if profile.MenuButton != nil {
+		if err := t.syncMenuButton(ctx, profile.MenuButton); err != nil {
+			return err
+		}
+	}
+
+	logrus.Info("bot profile synchronized")
+
+	return nil
+}
+
+func (t *TelegramClient) syncBotLocale(ctx context.Context, languageCode string, profileLocale BotProfileLocale) error {
+	fields := []struct {
+		value string
+		get   func(ctx context.Context, languageCode string) (string, error)
+		set   func(ctx context.Context, languageCode, value string) error
+	}{
+		{profileLocale.Name, t.GetBotName, t.SetBotName},
+		{profileLocale.Description, t.GetBotDescription, t.SetBotDescription},
+		{profileLocale.ShortDescription, t.GetBotShortDescription, t.SetBotShortDescription},
+	}
+
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+
+		current, err := field.get(ctx, languageCode)
+
+		if err != nil {
+			return err
+		}
+
+		if current == field.value {
+			continue
+		}
+
+		if err = field.set(ctx, languageCode, field.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *TelegramClient) syncMenuButton(ctx context.Context, button models.InputMenuButton) error {
+	current, err := t.GetChatMenuButton(ctx, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if menuButtonEqual(current, button) {
+		return nil
+	}
+
+	return t.SetChatMenuButton(ctx, 0, button)
+}
+
+// menuButtonEqual compares the buttons by their api representation.
+func menuButtonEqual(current *models.MenuButton, button models.InputMenuButton) bool {
+	currentData, err := json.Marshal(current)
+
+	if err != nil {
+		return false
+	}
+
+	buttonData, err := json.Marshal(button)
+
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(currentData, buttonData)
+}
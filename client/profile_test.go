@@ -0,0 +1,72 @@
+package client
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestSyncBotProfileSetsOnlyChanges(t *testing.T) {
+	var calls []string
+
+	client, _ := newTestClient(t, func(method string, _ *http.Request) any {
+		calls = append(calls, method)
+
+		switch method {
+		case "getMyName":
+			return map[string]any{"name": "Shop"}
+		case "getMyDescription":
+			return map[string]any{"description": "old"}
+		case "getMyCommands":
+			return []models.BotCommand{{Command: "start", Description: "Start"}}
+		case "getChatMenuButton":
+			return map[string]any{"type": "commands"}
+		}
+
+		return true
+	})
+
+	profile := &BotProfile{
+		Locales:    map[string]BotProfileLocale{"": {Name: "Shop", Description: "new"}},
+		Commands:   []ScopedCommands{{Commands: []models.BotCommand{{Command: "start", Description: "Start"}}}},
+		MenuButton: MenuButtonCommands(),
+	}
+
+	if err := client.SyncBotProfile(t.Context(), profile); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"getMyName", "getMyDescription", "setMyDescription", "getMyCommands", "getChatMenuButton"}
+
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("expected only changed description set, got %v", calls)
+	}
+
+	calls = nil
+	profile = &BotProfile{MenuButton: MenuButtonWebApp("Open", "https://example.com")}
+
+	if err := client.SyncBotProfile(t.Context(), profile); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected = []string{"getChatMenuButton", "setChatMenuButton"}; !slices.Equal(calls, expected) {
+		t.Fatalf("expected changed menu button set, got %v", calls)
+	}
+}
+
+func TestMenuButtonEqual(t *testing.T) {
+	current := &models.MenuButton{
+		Type:   models.MenuButtonTypeWebApp,
+		WebApp: &models.MenuButtonWebApp{Text: "Open", WebApp: models.WebAppInfo{URL: "https://example.com"}},
+	}
+
+	if !menuButtonEqual(current, MenuButtonWebApp("Open", "https://example.com")) {
+		t.Fatal("expected equal web app buttons")
+	}
+
+	if menuButtonEqual(current, MenuButtonWebApp("Open", "https://example.org")) || menuButtonEqual(current, MenuButtonDefault()) {
+		t.Fatal("expected different buttons")
+	}
+}
@@ -2,10 +2,18 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"path"
+	"strings"
 	"time"
+
+	"github.com/nejkit/telegram-bot-core/v2/config"
 )
 
 type apiResponse struct {
@@ -16,55 +24,255 @@ type apiResponse struct {
 	} `json:"parameters,omitempty"`
 }
 
+// nonIdempotentPrefixes lists telegram methods creating something on every call. Repeating them after
+// an ambiguous failure may e.g. send the message twice.
+var nonIdempotentPrefixes = []string{"send", "copy", "forward", "create", "export", "upload", "post", "gift", "transfer"}
+
+// IsIdempotentMethod reports whether the telegram method can be repeated when it is unknown
+// if the previous call was processed.
+func IsIdempotentMethod(method string) bool {
+	method = strings.ToLower(method)
+
+	for _, prefix := range nonIdempotentPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+const (
+	defaultRetryMaxAttempts     = 3
+	defaultRetryMinBackoff      = 500 * time.Millisecond
+	defaultRetryMaxBackoff      = 10 * time.Second
+	defaultRetryMaxDuration     = time.Minute
+	defaultRetryMaxBufferedBody = 10 << 20
+)
+
+// newRetryTransport converts the configured retry policy, zero values fall back to defaults and
+// negative ones disable the setting.
+func newRetryTransport(cfg *config.TelegramConfig) *RetryTransport {
+	return &RetryTransport{
+		Base:            http.DefaultTransport,
+		Retries:         retrySetting(cfg.RetryMaxAttempts, defaultRetryMaxAttempts),
+		MinBackoff:      retrySetting(cfg.RetryMinBackoff, defaultRetryMinBackoff),
+		MaxBackoff:      retrySetting(cfg.RetryMaxBackoff, defaultRetryMaxBackoff),
+		MaxDuration:     retrySetting(cfg.RetryMaxDuration, defaultRetryMaxDuration),
+		MaxBufferedBody: retrySetting(cfg.RetryMaxBufferedBody, defaultRetryMaxBufferedBody),
+	}
+}
+
+func retrySetting[T int | int64 | time.Duration](value, defaultValue T) T {
+	if value < 0 {
+		return 0
+	}
+
+	if value == 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// RetryTransport retries telegram api requests failed with network errors, server errors or flood wait.
+// Requests that may have been processed by telegram are retried only for idempotent methods, while
+// rejected ones (connection not established, 429) are retried for every method.
 type RetryTransport struct {
-	Base    http.RoundTripper
-	Retries int
-	Wait    time.Duration
+	Base http.RoundTripper
+	// Retries is the maximum amount of repeated attempts.
+	Retries    int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxDuration limits the total time spent on retries of one request, zero means no limit.
+	MaxDuration time.Duration
+	// MaxBufferedBody is the maximum size of the request body kept in memory to repeat requests
+	// without GetBody. Larger requests are sent once.
+	MaxBufferedBody int64
+	// IdempotentMethod overrides IsIdempotentMethod.
+	IdempotentMethod func(method string) bool
+	// FloodWaitHandler is called with the request context when telegram responds with retry_after.
+	FloodWaitHandler func(ctx context.Context, retryAfter time.Duration)
 }
 
 func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
+	ctx := req.Context()
+
+	req, retryable, err := t.rewindableRequest(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var deadline time.Time
+
+	if t.MaxDuration > 0 {
+		deadline = time.Now().Add(t.MaxDuration)
+	}
+
+	idempotent := t.isIdempotent(req)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
 
-	for i := 0; i <= t.Retries; i++ {
-		resp, err = t.Base.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
 
-		if err != nil {
-			time.Sleep(t.Wait)
-			continue
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := t.Base.RoundTrip(req)
+
+		wait, retry := t.classify(ctx, resp, err, idempotent, attempt)
+
+		if !retry || !retryable || attempt >= t.Retries || ctx.Err() != nil {
+			return resp, err
 		}
 
-		if resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout || resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusInternalServerError {
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
-			time.Sleep(t.Wait)
-			continue
 		}
 
-		if resp.StatusCode != 429 {
-			return resp, nil
+		if err = sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// classify decides whether the attempt result has to be retried and how long to wait before it.
+// The response body is restored, so it can be returned to the caller.
+func (t *RetryTransport) classify(ctx context.Context, resp *http.Response, err error, idempotent bool, attempt int) (time.Duration, bool) {
+	if err != nil {
+		var opErr *net.OpError
+
+		// the connection was not established, so telegram has not received the request
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return t.backoff(attempt), true
 		}
 
-		var tgError apiResponse
+		return t.backoff(attempt), idempotent
+	}
 
-		body, _ := io.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		body, readErr := io.ReadAll(resp.Body)
 		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
 
-		_ = json.Unmarshal(body, &tgError)
+		if readErr != nil {
+			return 0, false
+		}
 
-		if tgError.ErrorCode != 429 {
-			resp.Body = io.NopCloser(bytes.NewReader(body))
-			return resp, err
+		var tgError apiResponse
+
+		if json.Unmarshal(body, &tgError) != nil || tgError.ErrorCode != http.StatusTooManyRequests {
+			return 0, false
 		}
 
-		retryAfter := tgError.Parameters.RetryAfter
+		if tgError.Parameters.RetryAfter > 0 {
+			retryAfter := time.Duration(tgError.Parameters.RetryAfter) * time.Second
+
+			if t.FloodWaitHandler != nil {
+				t.FloodWaitHandler(ctx, retryAfter)
+			}
 
-		if retryAfter == 0 {
-			time.Sleep(t.Wait)
-			continue
+			return retryAfter, true
 		}
 
-		time.Sleep(time.Duration(retryAfter) * time.Second)
+		return t.backoff(attempt), true
+
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return t.backoff(attempt), idempotent
+	}
+
+	return 0, false
+}
+
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.MinBackoff << min(attempt, 30)
+
+	if delay <= 0 || (t.MaxBackoff > 0 && delay > t.MaxBackoff) {
+		delay = t.MaxBackoff
 	}
 
-	return resp, err
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (t *RetryTransport) isIdempotent(req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+
+	method := path.Base(req.URL.Path)
+
+	if t.IdempotentMethod != nil {
+		return t.IdempotentMethod(method)
+	}
+
+	return IsIdempotentMethod(method)
+}
+
+// rewindableRequest makes the request body readable once more for every retry. Requests without GetBody
+// are buffered up to MaxBufferedBody, larger ones are sent without retries.
+func (t *RetryTransport) rewindableRequest(req *http.Request) (*http.Request, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, true, nil
+	}
+
+	buffered, err := io.ReadAll(io.LimitReader(req.Body, t.MaxBufferedBody+1))
+
+	if err != nil {
+		req.Body.Close()
+		return nil, false, err
+	}
+
+	clone := req.Clone(req.Context())
+
+	if int64(len(buffered)) > t.MaxBufferedBody {
+		clone.Body = &multiReadCloser{
+			Reader: io.MultiReader(bytes.NewReader(buffered), req.Body),
+			Closer: req.Body,
+		}
+
+		return clone, false, nil
+	}
+
+	req.Body.Close()
+
+	clone.ContentLength = int64(len(buffered))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buffered)), nil
+	}
+	clone.Body, _ = clone.GetBody()
+
+	return clone, true, nil
+}
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func sleepCtx(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nejkit/telegram-bot-core/v2/config"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newRetryTestTransport(responses []int, bodies *[]string) *RetryTransport {
+	attempt := 0
+
+	return &RetryTransport{
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			*bodies = append(*bodies, string(body))
+
+			status := responses[min(attempt, len(responses)-1)]
+			attempt++
+
+			respBody := `{"ok":true}`
+
+			if status == http.StatusTooManyRequests {
+				respBody = `{"ok":false,"error_code":429,"parameters":{"retry_after":0}}`
+			}
+
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(respBody))}, nil
+		}),
+		Retries:         3,
+		MinBackoff:      time.Millisecond,
+		MaxBackoff:      time.Millisecond,
+		MaxBufferedBody: 1024,
+	}
+}
+
+func newRetryTestRequest(ctx context.Context, method string) *http.Request {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.telegram.org/bot123/"+method, io.NopCloser(strings.NewReader("payload")))
+
+	return req
+}
+
+func TestRetryTransportRewindsBody(t *testing.T) {
+	var bodies []string
+
+	transport := newRetryTestTransport([]int{http.StatusBadGateway, http.StatusOK}, &bodies)
+
+	resp, err := transport.RoundTrip(newRetryTestRequest(context.Background(), "editMessageText"))
+
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected result %v %v", resp, err)
+	}
+
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Fatalf("expected repeated body, got %q", bodies)
+	}
+}
+
+func TestRetryTransportSkipsAmbiguousNonIdempotent(t *testing.T) {
+	var bodies []string
+
+	transport := newRetryTestTransport([]int{http.StatusBadGateway, http.StatusOK}, &bodies)
+
+	resp, err := transport.RoundTrip(newRetryTestRequest(context.Background(), "sendMessage"))
+
+	if err != nil || resp.StatusCode != http.StatusBadGateway || len(bodies) != 1 {
+		t.Fatalf("expected single attempt, got %d attempts", len(bodies))
+	}
+
+	bodies = nil
+	transport = newRetryTestTransport([]int{http.StatusTooManyRequests, http.StatusOK}, &bodies)
+
+	resp, err = transport.RoundTrip(newRetryTestRequest(context.Background(), "sendMessage"))
+
+	if err != nil || resp.StatusCode != http.StatusOK || len(bodies) != 2 {
+		t.Fatalf("expected flood wait retry, got %d attempts", len(bodies))
+	}
+}
+
+func TestRetryTransportStopsOnContext(t *testing.T) {
+	var bodies []string
+
+	transport := newRetryTestTransport([]int{http.StatusServiceUnavailable}, &bodies)
+	transport.MinBackoff = time.Hour
+	transport.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := transport.RoundTrip(newRetryTestRequest(ctx, "getMe"))
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context error, got %v", err)
+	}
+}
+
+func TestNewRetryTransportDefaults(t *testing.T) {
+	transport := newRetryTransport(&config.TelegramConfig{})
+
+	if transport.Retries != defaultRetryMaxAttempts || transport.MinBackoff != defaultRetryMinBackoff ||
+		transport.MaxBackoff != defaultRetryMaxBackoff || transport.MaxDuration != defaultRetryMaxDuration ||
+		transport.MaxBufferedBody != defaultRetryMaxBufferedBody {
+		t.Fatalf("expected defaults for zero config, got %+v", transport)
+	}
+
+	transport = newRetryTransport(&config.TelegramConfig{RetryMaxAttempts: -1, RetryMaxDuration: -1})
+
+	if transport.Retries != 0 || transport.MaxDuration != 0 {
+		t.Fatalf("expected negative values to disable retries, got %+v", transport)
+	}
+}
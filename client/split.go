@@ -0,0 +1,588 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"html"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	MaxMessageLength = 4096
+	// MaxCaptionLength is the limit of media captions, see SendLongCaption.
+	MaxCaptionLength = 1024
+)
+
+type TextPart struct {
+	Text     string
+	Entities []models.MessageEntity
+}
+
+type splitPriority int
+
+const (
+	splitHard splitPriority = iota
+	splitWord
+	splitLine
+	splitParagraph
+)
+
+// splitTag describes formatting that must be closed at the end of a part and reopened at the start of the next one.
+type splitTag struct {
+	name   string
+	open   string
+	close  string
+	entity *models.MessageEntity
+}
+
+type splitAtom struct {
+	raw   string
+	text  string
+	open  *splitTag
+	close *splitTag
+}
+
+// SendLongMessage sends text exceeding MaxMessageLength as several messages split at paragraph, line or word
+// boundaries. Formatting is preserved across parts and reply markup is attached to the last part only.
+// Ids of already sent parts are returned together with the error.
+func (t *TelegramClient) SendLongMessage(ctx context.Context, recipientChatID int64, messageText string, options ...MessageOptions) ([]int, error) {
+	cfg := &bot.SendMessageParams{
+		ChatID: recipientChatID,
+		Text:   messageText,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	parts, err := SplitText(cfg.Text, cfg.ParseMode, cfg.Entities, MaxMessageLength)
+
+	if err != nil {
+		return nil, err
+	}
+
+	messageIDs := make([]int, 0, len(parts))
+
+	for i, part := range parts {
+		partCfg := *cfg
+		partCfg.Text = part.Text
+		partCfg.Entities = part.Entities
+
+		if i != len(parts)-1 {
+			partCfg.ReplyMarkup = nil
+		}
+
+		messageID, err := t.sendMessage(ctx, recipientChatID, &partCfg)
+
+		if err != nil {
+			return messageIDs, err
+		}
+
+		messageIDs = append(messageIDs, messageID)
+	}
+
+	return messageIDs, nil
+}
+
+// MediaSendFunc sends the media, e.g. TelegramClient.SendPhoto.
+type MediaSendFunc func(ctx context.Context, recipientChatID int64, file models.InputFile, options ...MediaOptions) (int, string, error)
+
+// SendLongCaption sends the media with the caption exceeding MaxCaptionLength. The media gets the first part of
+// the caption and the overflow is sent as text messages after it, split like SendLongMessage. Reply markup is
+// attached to the last message. Ids of all sent messages are returned together with the file id of the media.
+func (t *TelegramClient) SendLongCaption(ctx context.Context, recipientChatID int64, send MediaSendFunc, file models.InputFile, options ...MediaOptions) ([]int, string, error) {
+	cfg := newMediaParams(options)
+
+	parts, err := SplitText(cfg.Caption, cfg.ParseMode, cfg.CaptionEntities, MaxCaptionLength)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(parts) <= 1 {
+		messageID, fileID, err := send(ctx, recipientChatID, file, options...)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		return []int{messageID}, fileID, nil
+	}
+
+	messageID, fileID, err := send(ctx, recipientChatID, file, append(options, func(mediaCfg *MediaParams) {
+		mediaCfg.Caption = parts[0].Text
+		mediaCfg.CaptionEntities = parts[0].Entities
+		mediaCfg.ReplyMarkup = nil
+	})...)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	overflow := joinTextParts(parts[1:])
+
+	messageIDs, err := t.SendLongMessage(ctx, recipientChatID, overflow.Text, func(msgCfg *bot.SendMessageParams) {
+		msgCfg.ParseMode = cfg.ParseMode
+		msgCfg.Entities = overflow.Entities
+		msgCfg.ReplyMarkup = cfg.ReplyMarkup
+		msgCfg.MessageThreadID = cfg.MessageThreadID
+		msgCfg.DisableNotification = cfg.DisableNotification
+		msgCfg.ProtectContent = cfg.ProtectContent
+	})
+
+	return append([]int{messageID}, messageIDs...), fileID, err
+}
+
+// joinTextParts joins split parts with line breaks, every part has its formatting closed, so they are joined as is.
+func joinTextParts(parts []TextPart) TextPart {
+	var joined TextPart
+	offset := 0
+
+	for i, part := range parts {
+		if i > 0 {
+			joined.Text += "\n"
+			offset++
+		}
+
+		for _, entity := range part.Entities {
+			entity.Offset += offset
+			joined.Entities = append(joined.Entities, entity)
+		}
+
+		joined.Text += part.Text
+		offset += utf16Length(part.Text)
+	}
+
+	return joined
+}
+
+// SplitText splits text into parts having at most limit visible UTF-16 code units each.
+// Text is parsed according to parseMode, explicit entities are used when parse mode is empty.
+func SplitText(text string, parseMode models.ParseMode, entities []models.MessageEntity, limit int) ([]TextPart, error) {
+	var atoms []splitAtom
+	var err error
+
+	switch parseMode {
+	case models.ParseModeHTML:
+		atoms, err = htmlSplitAtoms(text)
+	case models.ParseModeMarkdown:
+		atoms, err = markdownSplitAtoms(text)
+	case "":
+		atoms = entitySplitAtoms(text, entities)
+	default:
+		return nil, errors.New("splitting is not supported for parse mode " + string(parseMode))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || visibleLength(atoms) <= limit {
+		return []TextPart{{Text: text, Entities: entities}}, nil
+	}
+
+	var parts []TextPart
+	var stack []*splitTag
+
+	for start := 0; start < len(atoms); {
+		end, next := findSplitPoint(atoms, start, limit)
+
+		part, endStack := buildTextPart(atoms[start:end], stack, parseMode == "")
+
+		if part.Text != "" {
+			parts = append(parts, part)
+		}
+
+		stack = endStack
+		start = next
+	}
+
+	return parts, nil
+}
+
+func findSplitPoint(atoms []splitAtom, start, limit int) (int, int) {
+	length := 0
+	bestEnd, bestNext, bestPriority := -1, -1, splitHard
+
+	for i := start; i < len(atoms); i++ {
+		atom := atoms[i]
+
+		if atom.text == "" {
+			continue
+		}
+
+		// separator is dropped from the part, so it is a candidate even when it does not fit itself
+		if priority, end := splitCandidate(atoms, start, i); priority != splitHard && priority >= bestPriority && end > start {
+			bestEnd, bestNext, bestPriority = end, i+1, priority
+		}
+
+		length += utf16Length(atom.text)
+
+		if length <= limit {
+			continue
+		}
+
+		if bestEnd > start {
+			return bestEnd, bestNext
+		}
+
+		// the part is at least one atom long so the loop always advances
+		return max(i, start+1), max(i, start+1)
+	}
+
+	return len(atoms), len(atoms)
+}
+
+func splitCandidate(atoms []splitAtom, start, idx int) (splitPriority, int) {
+	switch atoms[idx].text {
+	case "\n":
+		if prev := previousTextAtom(atoms, start, idx); prev >= 0 && atoms[prev].text == "\n" {
+			return splitParagraph, prev
+		}
+
+		return splitLine, idx
+	case " ", "\t":
+		return splitWord, idx
+	}
+
+	return splitHard, idx
+}
+
+func previousTextAtom(atoms []splitAtom, start, idx int) int {
+	for i := idx - 1; i >= start; i-- {
+		if atoms[i].open != nil || atoms[i].close != nil {
+			return -1
+		}
+
+		if atoms[i].text != "" {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func buildTextPart(atoms []splitAtom, stack []*splitTag, withEntities bool) (TextPart, []*splitTag) {
+	var sb strings.Builder
+	var entities []models.MessageEntity
+
+	stack = slices.Clone(stack)
+	starts := make(map[*splitTag]int)
+	offset := 0
+	hasText := false
+
+	closeTag := func(tag *splitTag) {
+		if !withEntities {
+			sb.WriteString(tag.close)
+			return
+		}
+
+		if length := offset - starts[tag]; length > 0 {
+			entity := *tag.entity
+			entity.Offset = starts[tag]
+			entity.Length = length
+			entities = append(entities, entity)
+		}
+	}
+
+	for _, tag := range stack {
+		sb.WriteString(tag.open)
+		starts[tag] = 0
+	}
+
+	for _, atom := range atoms {
+		switch {
+		case atom.open != nil:
+			stack = append(stack, atom.open)
+			starts[atom.open] = offset
+			sb.WriteString(atom.raw)
+		case atom.close != nil:
+			if idx := slices.Index(stack, atom.close); idx >= 0 {
+				stack = slices.Delete(stack, idx, idx+1)
+			}
+
+			if withEntities {
+				closeTag(atom.close)
+			}
+
+			sb.WriteString(atom.raw)
+		default:
+			sb.WriteString(atom.raw)
+			offset += utf16Length(atom.text)
+			hasText = hasText || strings.TrimSpace(atom.text) != ""
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		closeTag(stack[i])
+	}
+
+	if !hasText {
+		return TextPart{}, stack
+	}
+
+	slices.SortStableFunc(entities, func(a, b models.MessageEntity) int {
+		return a.Offset - b.Offset
+	})
+
+	return TextPart{Text: sb.String(), Entities: entities}, stack
+}
+
+func entitySplitAtoms(text string, entities []models.MessageEntity) []splitAtom {
+	tags := make([]*splitTag, len(entities))
+
+	for i := range entities {
+		tags[i] = &splitTag{entity: &entities[i]}
+	}
+
+	atoms := make([]splitAtom, 0, len(text))
+	offset := 0
+
+	emitTags := func() {
+		for i, entity := range entities {
+			if entity.Offset+entity.Length == offset && entity.Length > 0 {
+				atoms = append(atoms, splitAtom{close: tags[i]})
+			}
+		}
+
+		for i, entity := range entities {
+			if entity.Offset == offset && entity.Length > 0 {
+				atoms = append(atoms, splitAtom{open: tags[i]})
+			}
+		}
+	}
+
+	for _, r := range text {
+		emitTags()
+		atoms = append(atoms, splitAtom{raw: string(r), text: string(r)})
+		offset += utf16Length(string(r))
+	}
+
+	emitTags()
+
+	return atoms
+}
+
+func htmlSplitAtoms(text string) ([]splitAtom, error) {
+	var atoms []splitAtom
+	var stack []*splitTag
+
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			end := strings.IndexByte(text[i:], '>')
+
+			if end < 0 {
+				return nil, errors.New("unclosed html tag")
+			}
+
+			raw := text[i : i+end+1]
+			i += end + 1
+
+			if strings.HasPrefix(raw, "</") {
+				name := strings.ToLower(strings.TrimSpace(raw[2 : len(raw)-1]))
+
+				for j := len(stack) - 1; j >= 0; j-- {
+					if stack[j].name == name {
+						atoms = append(atoms, splitAtom{raw: raw, close: stack[j]})
+						stack = slices.Delete(stack, j, j+1)
+						break
+					}
+				}
+
+				continue
+			}
+
+			fields := strings.Fields(raw[1 : len(raw)-1])
+
+			if len(fields) == 0 {
+				return nil, errors.New("empty html tag")
+			}
+
+			name := strings.ToLower(fields[0])
+			tag := &splitTag{name: name, open: raw, close: "</" + name + ">"}
+			stack = append(stack, tag)
+			atoms = append(atoms, splitAtom{raw: raw, open: tag})
+		case '&':
+			end := strings.IndexByte(text[i:], ';')
+
+			if end < 0 {
+				atoms = append(atoms, splitAtom{raw: "&", text: "&"})
+				i++
+				continue
+			}
+
+			raw := text[i : i+end+1]
+			atoms = append(atoms, splitAtom{raw: raw, text: html.UnescapeString(raw)})
+			i += end + 1
+		default:
+			r, size := utf8.DecodeRuneInString(text[i:])
+			atoms = append(atoms, splitAtom{raw: text[i : i+size], text: string(r)})
+			i += size
+		}
+	}
+
+	return atoms, nil
+}
+
+var markdownToggles = []string{"||", "__", "*", "_", "~"}
+
+func markdownSplitAtoms(text string) ([]splitAtom, error) {
+	var atoms []splitAtom
+	var stack []*splitTag
+
+	top := func() *splitTag {
+		if len(stack) == 0 {
+			return nil
+		}
+
+		return stack[len(stack)-1]
+	}
+
+	openTag := func(raw string, tag *splitTag) {
+		stack = append(stack, tag)
+		atoms = append(atoms, splitAtom{raw: raw, open: tag})
+	}
+
+	closeTag := func(raw string, idx int) {
+		atoms = append(atoms, splitAtom{raw: raw, close: stack[idx]})
+		stack = slices.Delete(stack, idx, idx+1)
+	}
+
+	for i := 0; i < len(text); {
+		current := top()
+		inCode := current != nil && (current.name == "pre" || current.name == "code")
+
+		if text[i] == '\\' && i+1 < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i+1:])
+			atoms = append(atoms, splitAtom{raw: text[i : i+1+size], text: string(r)})
+			i += 1 + size
+			continue
+		}
+
+		switch {
+		case inCode && current.name == "pre" && strings.HasPrefix(text[i:], "```"):
+			closeTag("```", len(stack)-1)
+			i += 3
+			continue
+		case inCode && current.name == "code" && text[i] == '`':
+			closeTag("`", len(stack)-1)
+			i++
+			continue
+		case inCode:
+		case strings.HasPrefix(text[i:], "```"):
+			lineEnd := strings.IndexByte(text[i:], '\n')
+
+			if lineEnd < 0 {
+				return nil, errors.New("unclosed markdown pre block")
+			}
+
+			raw := text[i : i+lineEnd+1]
+			openTag(raw, &splitTag{name: "pre", open: raw, close: "```"})
+			i += lineEnd + 1
+			continue
+		case text[i] == '`':
+			openTag("`", &splitTag{name: "code", open: "`", close: "`"})
+			i++
+			continue
+		case text[i] == '[' || strings.HasPrefix(text[i:], "!["):
+			raw := "["
+
+			if text[i] == '!' {
+				raw = "!["
+			}
+
+			openTag(raw, &splitTag{name: "link", open: raw, close: markdownLinkTail(text[i+len(raw):])})
+			i += len(raw)
+			continue
+		case text[i] == ']' && slices.ContainsFunc(stack, func(tag *splitTag) bool { return tag.name == "link" }):
+			idx := slices.IndexFunc(stack, func(tag *splitTag) bool { return tag.name == "link" })
+			tail := stack[idx].close
+
+			closeTag(tail, idx)
+			i += max(len(tail), 1)
+			continue
+		case text[i] == '>' && (i == 0 || text[i-1] == '\n'):
+			atoms = append(atoms, splitAtom{raw: ">"})
+			i++
+			continue
+		default:
+			toggle := ""
+
+			for _, candidate := range markdownToggles {
+				if strings.HasPrefix(text[i:], candidate) {
+					toggle = candidate
+					break
+				}
+			}
+
+			if toggle == "" {
+				break
+			}
+
+			if idx := slices.IndexFunc(stack, func(tag *splitTag) bool { return tag.name == toggle }); idx >= 0 {
+				closeTag(toggle, idx)
+			} else {
+				openTag(toggle, &splitTag{name: toggle, open: toggle, close: toggle})
+			}
+
+			i += len(toggle)
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		atoms = append(atoms, splitAtom{raw: text[i : i+size], text: string(r)})
+		i += size
+	}
+
+	return atoms, nil
+}
+
+// markdownLinkTail returns the "](url)" part following the link text.
+func markdownLinkTail(text string) string {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case strings.HasPrefix(text[i:], "]("):
+			for j := i + 2; j < len(text); j++ {
+				switch text[j] {
+				case '\\':
+					j++
+				case ')':
+					return text[i : j+1]
+				}
+			}
+
+			return "]"
+		}
+	}
+
+	return "]"
+}
+
+func visibleLength(atoms []splitAtom) int {
+	length := 0
+
+	for _, atom := range atoms {
+		length += utf16Length(atom.text)
+	}
+
+	return length
+}
+
+func utf16Length(text string) int {
+	length := 0
+
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+	}
+
+	return length
+}
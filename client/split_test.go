@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestSplitTextPrefersParagraphs(t *testing.T) {
+	text := strings.Repeat("a", 6) + "\n\n" + strings.Repeat("b", 6) + " " + strings.Repeat("c", 3)
+
+	parts, err := SplitText(text, "", nil, 12)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 2 || parts[0].Text != "aaaaaa" || parts[1].Text != "bbbbbb ccc" {
+		t.Errorf("unexpected parts: %+v", parts)
+	}
+}
+
+func TestSplitTextHTMLReopensTags(t *testing.T) {
+	parts, err := SplitText("<b>one two &amp; three</b>", models.ParseModeHTML, nil, 8)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 2 || parts[0].Text != "<b>one two</b>" || parts[1].Text != "<b>&amp; three</b>" {
+		t.Errorf("unexpected parts: %+v", parts)
+	}
+}
+
+func TestSplitTextMarkdownLink(t *testing.T) {
+	parts, err := SplitText("[one two](https://x\\.y) *end*", models.ParseModeMarkdown, nil, 7)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 2 || parts[0].Text != "[one two](https://x\\.y)" || parts[1].Text != "*end*" {
+		t.Errorf("unexpected parts: %+v", parts)
+	}
+}
+
+func TestSplitTextEntities(t *testing.T) {
+	entities := []models.MessageEntity{{Type: models.MessageEntityTypeBold, Offset: 3, Length: 9}}
+
+	parts, err := SplitText("😀 bold text", "", entities, 7)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 2 || parts[0].Text != "😀 bold" || parts[1].Text != "text" {
+		t.Fatalf("unexpected parts: %+v", parts)
+	}
+
+	if e := parts[0].Entities; len(e) != 1 || e[0].Offset != 3 || e[0].Length != 4 {
+		t.Errorf("unexpected first part entities: %+v", e)
+	}
+
+	if e := parts[1].Entities; len(e) != 1 || e[0].Offset != 0 || e[0].Length != 4 {
+		t.Errorf("unexpected second part entities: %+v", e)
+	}
+}
+
+func TestSendLongCaption(t *testing.T) {
+	var caption, overflow, photoMarkup, textMarkup string
+	nextID := 0
+
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		_ = r.ParseMultipartForm(1 << 20)
+		nextID++
+
+		switch method {
+		case "sendPhoto":
+			caption, photoMarkup = r.FormValue("caption"), r.FormValue("reply_markup")
+			return models.Message{ID: nextID, Photo: []models.PhotoSize{{FileID: "photo-id"}}}
+		case "sendMessage":
+			overflow, textMarkup = r.FormValue("text"), r.FormValue("reply_markup")
+		}
+
+		return models.Message{ID: nextID}
+	})
+
+	text := strings.Repeat("word ", 300)
+	keyboard := models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{{Text: "ok", CallbackData: "ok"}}}}
+
+	messageIDs, fileID, err := client.SendLongCaption(context.Background(), 1, client.SendPhoto, FileByID("photo-id"),
+		WithMediaCaption("<b>"+text+"</b>"), WithMediaParseMode(models.ParseModeHTML), WithMediaInlineKeyboard(keyboard))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messageIDs) != 2 || fileID != "photo-id" {
+		t.Fatalf("expected photo and one text message, got %v, %s", messageIDs, fileID)
+	}
+
+	if utf8.RuneCountInString(caption)-len("<b></b>") > MaxCaptionLength || !strings.HasPrefix(caption, "<b>") || !strings.HasPrefix(overflow, "<b>") {
+		t.Fatalf("expected formatted caption within the limit and formatted overflow, got %q and %q", caption, overflow)
+	}
+
+	if photoMarkup != "" || textMarkup == "" {
+		t.Fatal("expected reply markup on the last message only")
+	}
+}
@@ -1,20 +1,18 @@
 package client
 
 import (
+	"bytes"
 	"context"
-	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
-	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
 	"github.com/nejkit/telegram-bot-core/v2/limiter"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
@@ -26,11 +24,25 @@ type TelegramClient struct {
 	api            *bot.Bot
 	allowedUpdates []string
 
-	chatLimiter   *limiter.UserLimiter
+	httpClient      *http.Client
+	apiUrl          string
+	maxDownloadSize int64
+
+	chatLimiter   *limiter.ChatLimiter
 	globalLimiter *rate.Limiter
 
-	updates   chan *models.Update
-	startOnce sync.Once
+	updates    chan *models.Update
+	updatesErr chan error
+	startOnce  sync.Once
+
+	webhook *webhookConfig
+
+	paymentProviderToken string
+
+	ignoredErrors  []error
+	blockedHandler BlockedHandlerFunc
+
+	botMembers botMemberCache
 }
 
 type MessageOptions func(msgCfg *bot.SendMessageParams)
@@ -54,6 +66,33 @@ func WithRemoveReplyKeyboard() MessageOptions {
 	}
 }
 
+func WithSendParseMode(parseMode models.ParseMode) MessageOptions {
+	return func(msgCfg *bot.SendMessageParams) {
+		msgCfg.ParseMode = parseMode
+	}
+}
+
+func WithSendEntities(entities []models.MessageEntity) MessageOptions {
+	return func(msgCfg *bot.SendMessageParams) {
+		msgCfg.Entities = entities
+	}
+}
+
+// WithSendThread sends the message to the forum topic, without it messages land in the general topic.
+func WithSendThread(threadID int) MessageOptions {
+	return func(msgCfg *bot.SendMessageParams) {
+		msgCfg.MessageThreadID = threadID
+	}
+}
+
+func WithSendHTML() MessageOptions {
+	return WithSendParseMode(models.ParseModeHTML)
+}
+
+func WithSendMarkdownV2() MessageOptions {
+	return WithSendParseMode(models.ParseModeMarkdown)
+}
+
 func WithEditInlineKeyboard(keyboard models.InlineKeyboardMarkup) EditMessageOptions {
 	return func(msgCfg *bot.EditMessageTextParams) {
 		msgCfg.ReplyMarkup = keyboard
@@ -66,6 +105,26 @@ func WithEditMessageText(text string) EditMessageOptions {
 	}
 }
 
+func WithEditParseMode(parseMode models.ParseMode) EditMessageOptions {
+	return func(msgCfg *bot.EditMessageTextParams) {
+		msgCfg.ParseMode = parseMode
+	}
+}
+
+func WithEditEntities(entities []models.MessageEntity) EditMessageOptions {
+	return func(msgCfg *bot.EditMessageTextParams) {
+		msgCfg.Entities = entities
+	}
+}
+
+func WithEditHTML() EditMessageOptions {
+	return WithEditParseMode(models.ParseModeHTML)
+}
+
+func WithEditMarkdownV2() EditMessageOptions {
+	return WithEditParseMode(models.ParseModeMarkdown)
+}
+
 type DownloadFileInfo struct {
 	FileName string
 	MimoType string
@@ -73,21 +132,38 @@ type DownloadFileInfo struct {
 }
 
 func NewTelegramClient(cfg *config.TelegramConfig) *TelegramClient {
-	transport := &RetryTransport{
-		Base:    http.DefaultTransport,
-		Retries: 3,
-		Wait:    time.Second,
+	t, err := CreateTelegramClient(cfg)
+
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating telegram client")
 	}
 
+	return t
+}
+
+// CreateTelegramClient creates the client like NewTelegramClient, but returns the error instead of
+// stopping the process, e.g. for bots added at runtime.
+func CreateTelegramClient(cfg *config.TelegramConfig) (*TelegramClient, error) {
+	transport := newRetryTransport(cfg)
+
 	httpClient := &http.Client{Transport: transport}
 
 	t := &TelegramClient{
-		allowedUpdates: cfg.AllowedUpdates,
-		globalLimiter:  rate.NewLimiter(25, 25),
-		chatLimiter:    limiter.NewUserLimiter(rate.Every(time.Second), 2),
-		updates:        make(chan *models.Update, updatesBufferSize),
+		allowedUpdates:  cfg.AllowedUpdates,
+		httpClient:      httpClient,
+		apiUrl:          cfg.TelegramApiUrl,
+		maxDownloadSize: cfg.MaxDownloadFileSize,
+		globalLimiter:   newGlobalLimiter(cfg),
+		chatLimiter:     newChatLimiter(cfg),
+		updates:         make(chan *models.Update, updatesBufferSize),
+		updatesErr:      make(chan error, 1),
+		ignoredErrors:   []error{domain.ErrorBotBlocked, domain.ErrorUserDeactivated},
+
+		paymentProviderToken: cfg.PaymentProviderToken,
 	}
 
+	transport.FloodWaitHandler = t.onFloodWait
+
 	opts := []bot.Option{
 		bot.WithHTTPClient(time.Minute, httpClient),
 		bot.WithDefaultHandler(t.bridgeHandler),
@@ -104,15 +180,24 @@ func NewTelegramClient(cfg *config.TelegramConfig) *TelegramClient {
 	botApi, err := bot.New(cfg.Token, opts...)
 
 	if err != nil {
-		logrus.WithError(err).Fatal("Error creating telegram client")
+		return nil, err
 	}
 
 	t.api = botApi
 
-	return t
+	t.webhook, err = newWebhookConfig(cfg.WebhookURL, cfg.WebhookListenAddr, cfg.WebhookSecret)
+
+	if err != nil {
+		return nil, fmt.Errorf("parse webhook url: %w", err)
+	}
+
+	return t, nil
 }
 
 func (t *TelegramClient) bridgeHandler(ctx context.Context, _ *bot.Bot, update *models.Update) {
+	t.observeChatType(update)
+	t.observeBotMember(update)
+
 	select {
 	case <-ctx.Done():
 		return
@@ -137,7 +222,7 @@ func (t *TelegramClient) ValidateWebAppInitData(initData string) (int64, error)
 }
 
 func (t *TelegramClient) RunChatRatesCleanup(ctx context.Context) {
-	go t.chatLimiter.Run(ctx)
+	t.chatLimiter.Run(ctx)
 }
 
 func (t *TelegramClient) SendMessage(ctx context.Context, recipientChatID int64, messageText string, options ...MessageOptions) (int, error) {
@@ -150,16 +235,20 @@ func (t *TelegramClient) SendMessage(ctx context.Context, recipientChatID int64,
 		opt(cfg)
 	}
 
-	if err := t.globalLimiter.Wait(ctx); err != nil {
+	return t.sendMessage(ctx, recipientChatID, cfg)
+}
+
+func (t *TelegramClient) sendMessage(ctx context.Context, recipientChatID int64, cfg *bot.SendMessageParams) (int, error) {
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
 		return 0, err
 	}
 
-	t.chatLimiter.Wait(ctx, recipientChatID)
-
 	response, err := t.api.SendMessage(ctx, cfg)
 
 	if err != nil {
-		return 0, t.handleError(err)
+		return 0, t.handleError(ctx, recipientChatID, err)
 	}
 
 	return response.ID, nil
@@ -175,15 +264,15 @@ func (t *TelegramClient) EditMessage(ctx context.Context, recipientChatID int64,
 		opt(cfg)
 	}
 
-	if err := t.globalLimiter.Wait(ctx); err != nil {
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
 		return err
 	}
 
-	t.chatLimiter.Wait(ctx, recipientChatID)
-
-	_, err := t.api.EditMessageText(ctx, cfg)
+	_, err = t.api.EditMessageText(ctx, cfg)
 
-	return t.handleError(err)
+	return t.handleError(ctx, recipientChatID, err)
 }
 
 func (t *TelegramClient) EditMessageKeyboard(ctx context.Context, recipientChatID int64, messageID int, keyboard *models.InlineKeyboardMarkup) error {
@@ -196,86 +285,62 @@ func (t *TelegramClient) EditMessageKeyboard(ctx context.Context, recipientChatI
 		cfg.ReplyMarkup = *keyboard
 	}
 
-	if err := t.globalLimiter.Wait(ctx); err != nil {
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
 		return err
 	}
 
-	t.chatLimiter.Wait(ctx, recipientChatID)
-
-	_, err := t.api.EditMessageReplyMarkup(ctx, cfg)
+	_, err = t.api.EditMessageReplyMarkup(ctx, cfg)
 
-	return t.handleError(err)
+	return t.handleError(ctx, recipientChatID, err)
 }
 
 func (t *TelegramClient) DeleteMessage(ctx context.Context, recipientChatID int64, messageID int) error {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
 		return err
 	}
 
-	t.chatLimiter.Wait(ctx, recipientChatID)
-
-	_, err := t.api.DeleteMessage(ctx, &bot.DeleteMessageParams{
+	_, err = t.api.DeleteMessage(ctx, &bot.DeleteMessageParams{
 		ChatID:    recipientChatID,
 		MessageID: messageID,
 	})
 
-	return t.handleError(err)
+	return t.handleError(ctx, recipientChatID, err)
 }
 
 func (t *TelegramClient) UploadFile(ctx context.Context, recipientChatID int64, fileName string, fileContent []byte) (int, string, error) {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
-		return 0, "", err
-	}
-
-	t.chatLimiter.Wait(ctx, recipientChatID)
-
-	response, err := t.api.SendDocument(ctx, &bot.SendDocumentParams{
-		ChatID: recipientChatID,
-		Document: &models.InputFileUpload{
-			Filename: fileName,
-			Data:     strings.NewReader(string(fileContent)),
-		},
-	})
-
-	if err != nil {
-		return 0, "", t.handleError(err)
-	}
-
-	var fileID string
-
-	if response.Document != nil {
-		fileID = response.Document.FileID
-	}
-
-	return response.ID, fileID, nil
+	return t.UploadDocument(ctx, recipientChatID, fileName, bytes.NewReader(fileContent))
 }
 
 func (t *TelegramClient) SendFileByID(ctx context.Context, recipientChatID int64, fileID string) (int, error) {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
+	ctx, err := t.waitChatLimits(ctx, recipientChatID)
+
+	if err != nil {
 		return 0, err
 	}
 
-	t.chatLimiter.Wait(ctx, recipientChatID)
-
 	response, err := t.api.SendDocument(ctx, &bot.SendDocumentParams{
 		ChatID:   recipientChatID,
 		Document: &models.InputFileString{Data: fileID},
 	})
 
 	if err != nil {
-		return 0, t.handleError(err)
+		return 0, t.handleError(ctx, recipientChatID, err)
 	}
 
 	return response.ID, nil
 }
 
 func (t *TelegramClient) CopyMessage(ctx context.Context, fromChatID, toChatID int64, messageID int) (int, error) {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
+	ctx, err := t.waitChatLimits(ctx, toChatID)
+
+	if err != nil {
 		return 0, err
 	}
 
-	t.chatLimiter.Wait(ctx, toChatID)
-
 	response, err := t.api.CopyMessage(ctx, &bot.CopyMessageParams{
 		ChatID:     toChatID,
 		FromChatID: fromChatID,
@@ -283,50 +348,42 @@ func (t *TelegramClient) CopyMessage(ctx context.Context, fromChatID, toChatID i
 	})
 
 	if err != nil {
-		return 0, t.handleError(err)
+		return 0, t.handleError(ctx, toChatID, err)
 	}
 
 	return response.ID, nil
 }
 
 func (t *TelegramClient) DownloadFile(ctx context.Context, fileID string) (*DownloadFileInfo, error) {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
-		return nil, err
-	}
+	var buffer bytes.Buffer
 
-	file, err := t.api.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
-
-	if err != nil {
-		return nil, t.handleError(err)
-	}
-
-	httpResp, err := http.Get(t.api.FileDownloadLink(file))
+	stat, err := t.DownloadFileTo(ctx, fileID, &buffer)
 
 	if err != nil {
 		return nil, err
 	}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			logrus.WithError(err).Error("failed to close file reader")
-		}
-	}(httpResp.Body)
-
-	body, err := io.ReadAll(httpResp.Body)
+	return &DownloadFileInfo{
+		FileName: stat.FileName,
+		MimoType: stat.MimoType,
+		FileData: buffer.Bytes(),
+	}, nil
+}
 
-	if err != nil {
-		return nil, err
+// SendChatAction shows the action, e.g. typing, in the chat for about 5 seconds or until the next message.
+// Zero thread id shows it in the general topic.
+func (t *TelegramClient) SendChatAction(ctx context.Context, chatID int64, threadID int, action models.ChatAction) error {
+	if err := t.globalLimiter.Wait(ctx); err != nil {
+		return err
 	}
 
-	mimoType := http.DetectContentType(body)
-	fileName := filepath.Base(file.FilePath)
+	_, err := t.api.SendChatAction(ctx, &bot.SendChatActionParams{
+		ChatID:          chatID,
+		MessageThreadID: threadID,
+		Action:          action,
+	})
 
-	return &DownloadFileInfo{
-		FileName: fileName,
-		MimoType: mimoType,
-		FileData: body,
-	}, nil
+	return t.handleError(ctx, chatID, err)
 }
 
 func (t *TelegramClient) AnswerCallback(ctx context.Context, callbackID, messageText string) error {
@@ -335,7 +392,7 @@ func (t *TelegramClient) AnswerCallback(ctx context.Context, callbackID, message
 		Text:            messageText,
 	})
 
-	return t.handleError(err)
+	return t.handleError(ctx, 0, err)
 }
 
 func (t *TelegramClient) GetInviteLink(ctx context.Context, secret string) (string, error) {
@@ -346,7 +403,7 @@ func (t *TelegramClient) GetInviteLink(ctx context.Context, secret string) (stri
 	me, err := t.api.GetMe(ctx)
 
 	if err != nil {
-		return "", t.handleError(err)
+		return "", t.handleError(ctx, 0, err)
 	}
 
 	return fmt.Sprintf("https://telegram.me/%s?start=%s", me.Username, secret), nil
@@ -361,60 +418,39 @@ func (t *TelegramClient) AnswerCallbackQuery(ctx context.Context, callbackID str
 		CallbackQueryID: callbackID,
 	})
 
-	return t.handleError(err)
+	return t.handleError(ctx, 0, err)
 }
 
 func (t *TelegramClient) ProcessChatJoinRequest(ctx context.Context, chatID int64, userID int64, accept bool) error {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
+	ctx, err := t.waitChatLimits(ctx, chatID)
+
+	if err != nil {
 		return err
 	}
 
-	t.chatLimiter.Wait(ctx, chatID)
-
 	if accept {
-		_, err := t.api.ApproveChatJoinRequest(ctx, &bot.ApproveChatJoinRequestParams{
+		_, err = t.api.ApproveChatJoinRequest(ctx, &bot.ApproveChatJoinRequestParams{
 			ChatID: chatID,
 			UserID: userID,
 		})
 
-		return t.handleError(err)
+		return t.handleError(ctx, chatID, err)
 	}
 
-	_, err := t.api.DeclineChatJoinRequest(ctx, &bot.DeclineChatJoinRequestParams{
+	_, err = t.api.DeclineChatJoinRequest(ctx, &bot.DeclineChatJoinRequestParams{
 		ChatID: chatID,
 		UserID: userID,
 	})
 
-	return t.handleError(err)
+	return t.handleError(ctx, chatID, err)
 }
 
 func (t *TelegramClient) GetBotCommands(ctx context.Context, fromChatID int64) ([]models.BotCommand, error) {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
-	commands, err := t.api.GetMyCommands(ctx, &bot.GetMyCommandsParams{
-		Scope: &models.BotCommandScopeChat{ChatID: fromChatID},
-	})
-
-	if err != nil {
-		return nil, t.handleError(err)
-	}
-
-	return commands, nil
+	return t.GetScopedBotCommands(ctx, &models.BotCommandScopeChat{ChatID: fromChatID}, "")
 }
 
 func (t *TelegramClient) SetBotCommands(ctx context.Context, toChatID int64, commands []models.BotCommand) error {
-	if err := t.globalLimiter.Wait(ctx); err != nil {
-		return err
-	}
-
-	_, err := t.api.SetMyCommands(ctx, &bot.SetMyCommandsParams{
-		Commands: commands,
-		Scope:    &models.BotCommandScopeChat{ChatID: toChatID},
-	})
-
-	return t.handleError(err)
+	return t.SetScopedBotCommands(ctx, &models.BotCommandScopeChat{ChatID: toChatID}, "", commands)
 }
 
 func (t *TelegramClient) KickUserFromChat(ctx context.Context, fromChatID, userID int64, withBan bool) error {
@@ -428,7 +464,7 @@ func (t *TelegramClient) KickUserFromChat(ctx context.Context, fromChatID, userI
 	})
 
 	if err != nil || withBan {
-		return t.handleError(err)
+		return t.handleError(ctx, fromChatID, err)
 	}
 
 	_, err = t.api.UnbanChatMember(ctx, &bot.UnbanChatMemberParams{
@@ -437,7 +473,7 @@ func (t *TelegramClient) KickUserFromChat(ctx context.Context, fromChatID, userI
 		OnlyIfBanned: true,
 	})
 
-	return t.handleError(err)
+	return t.handleError(ctx, fromChatID, err)
 }
 
 func (t *TelegramClient) GetContactInfo(ctx context.Context, userID int64) (*models.ChatFullInfo, error) {
@@ -448,7 +484,7 @@ func (t *TelegramClient) GetContactInfo(ctx context.Context, userID int64) (*mod
 	contact, err := t.api.GetChat(ctx, &bot.GetChatParams{ChatID: userID})
 
 	if err != nil {
-		return nil, t.handleError(err)
+		return nil, t.handleError(ctx, userID, err)
 	}
 
 	return contact, nil
@@ -465,7 +501,7 @@ func (t *TelegramClient) CheckUserExistenceInChat(ctx context.Context, chatID, u
 	})
 
 	if err != nil {
-		return false, t.handleError(err)
+		return false, t.handleError(ctx, chatID, err)
 	}
 
 	switch member.Type {
@@ -478,24 +514,23 @@ func (t *TelegramClient) CheckUserExistenceInChat(ctx context.Context, chatID, u
 
 func (t *TelegramClient) GetUpdates(ctx context.Context) <-chan *models.Update {
 	t.startOnce.Do(func() {
-		go t.api.Start(ctx)
+		if t.webhook != nil {
+			go t.startWebhook(ctx)
+			return
+		}
+
+		go t.startPolling(ctx)
 	})
 
 	return t.updates
 }
 
-func (t *TelegramClient) handleError(err error) error {
-	if err == nil {
-		return nil
-	}
-
-	if errors.Is(err, bot.ErrorForbidden) {
-		msg := err.Error()
-		if strings.Contains(msg, "bot was blocked by the user") ||
-			strings.Contains(msg, "user is deactivated") {
-			return nil
-		}
-	}
+// UpdatesErr receives the error which stopped receiving of updates started by GetUpdates, e.g. the webhook
+// rejected by telegram or the webhook server failed to listen.
+func (t *TelegramClient) UpdatesErr() <-chan error {
+	return t.updatesErr
+}
 
-	return err
+func (t *TelegramClient) GlobalRateLimit() rate.Limit {
+	return t.globalLimiter.Limit()
 }
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const markdownV2SpecialChars = "_*[]()~`>#+-=|{}.!\\"
+
+// EscapeHTML escapes text to be safely placed inside HTML formatted message.
+func EscapeHTML(text string) string {
+	return html.EscapeString(text)
+}
+
+// EscapeMarkdownV2 escapes every character reserved by MarkdownV2 outside of code blocks and links.
+func EscapeMarkdownV2(text string) string {
+	return escapeChars(text, markdownV2SpecialChars)
+}
+
+func escapeChars(text, chars string) string {
+	var sb strings.Builder
+
+	for _, r := range text {
+		if strings.ContainsRune(chars, r) {
+			sb.WriteRune('\\')
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// TextBuilder builds formatted message text. All user supplied content is escaped for the chosen parse mode.
+// With empty parse mode the builder produces plain text together with message entities.
+type TextBuilder struct {
+	parseMode models.ParseMode
+	sb        strings.Builder
+	entities  []models.MessageEntity
+	offset    int
+}
+
+func NewTextBuilder(parseMode models.ParseMode) *TextBuilder {
+	return &TextBuilder{parseMode: parseMode}
+}
+
+func NewHTMLBuilder() *TextBuilder {
+	return NewTextBuilder(models.ParseModeHTML)
+}
+
+func NewMarkdownV2Builder() *TextBuilder {
+	return NewTextBuilder(models.ParseModeMarkdown)
+}
+
+func NewEntitiesBuilder() *TextBuilder {
+	return NewTextBuilder("")
+}
+
+func (b *TextBuilder) Text(text string) *TextBuilder {
+	switch b.parseMode {
+	case models.ParseModeHTML:
+		b.sb.WriteString(EscapeHTML(text))
+	case models.ParseModeMarkdown:
+		b.sb.WriteString(EscapeMarkdownV2(text))
+	default:
+		b.writePlain(text)
+	}
+
+	return b
+}
+
+func (b *TextBuilder) Textf(format string, args ...any) *TextBuilder {
+	return b.Text(fmt.Sprintf(format, args...))
+}
+
+func (b *TextBuilder) Line(text string) *TextBuilder {
+	return b.Text(text + "\n")
+}
+
+func (b *TextBuilder) Bold(text string) *TextBuilder {
+	return b.wrap(text, "<b>", "</b>", "*", "*", models.MessageEntity{Type: models.MessageEntityTypeBold})
+}
+
+func (b *TextBuilder) Italic(text string) *TextBuilder {
+	return b.wrap(text, "<i>", "</i>", "_", "_", models.MessageEntity{Type: models.MessageEntityTypeItalic})
+}
+
+func (b *TextBuilder) Underline(text string) *TextBuilder {
+	return b.wrap(text, "<u>", "</u>", "__", "__", models.MessageEntity{Type: models.MessageEntityTypeUnderline})
+}
+
+func (b *TextBuilder) Strikethrough(text string) *TextBuilder {
+	return b.wrap(text, "<s>", "</s>", "~", "~", models.MessageEntity{Type: models.MessageEntityTypeStrikethrough})
+}
+
+func (b *TextBuilder) Spoiler(text string) *TextBuilder {
+	return b.wrap(text, "<tg-spoiler>", "</tg-spoiler>", "||", "||", models.MessageEntity{Type: models.MessageEntityTypeSpoiler})
+}
+
+func (b *TextBuilder) Code(text string) *TextBuilder {
+	if b.parseMode == models.ParseModeMarkdown {
+		b.sb.WriteString("`" + escapeChars(text, "`\\") + "`")
+		return b
+	}
+
+	return b.wrap(text, "<code>", "</code>", "", "", models.MessageEntity{Type: models.MessageEntityTypeCode})
+}
+
+func (b *TextBuilder) Pre(text, language string) *TextBuilder {
+	switch b.parseMode {
+	case models.ParseModeHTML:
+		if language == "" {
+			b.sb.WriteString("<pre>" + EscapeHTML(text) + "</pre>")
+			return b
+		}
+
+		b.sb.WriteString(fmt.Sprintf(`<pre><code class="language-%s">%s</code></pre>`, EscapeHTML(language), EscapeHTML(text)))
+	case models.ParseModeMarkdown:
+		b.sb.WriteString("```" + escapeChars(language, "`\\") + "\n" + escapeChars(text, "`\\") + "\n```")
+	default:
+		b.writeEntity(text, models.MessageEntity{Type: models.MessageEntityTypePre, Language: language})
+	}
+
+	return b
+}
+
+func (b *TextBuilder) Blockquote(text string) *TextBuilder {
+	if b.parseMode == models.ParseModeMarkdown {
+		lines := strings.Split(text, "\n")
+
+		for i, line := range lines {
+			lines[i] = ">" + EscapeMarkdownV2(line)
+		}
+
+		b.sb.WriteString(strings.Join(lines, "\n"))
+		return b
+	}
+
+	return b.wrap(text, "<blockquote>", "</blockquote>", "", "", models.MessageEntity{Type: models.MessageEntityTypeBlockquote})
+}
+
+func (b *TextBuilder) Link(text, url string) *TextBuilder {
+	switch b.parseMode {
+	case models.ParseModeHTML:
+		b.sb.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, EscapeHTML(url), EscapeHTML(text)))
+	case models.ParseModeMarkdown:
+		b.sb.WriteString("[" + EscapeMarkdownV2(text) + "](" + escapeChars(url, ")\\") + ")")
+	default:
+		b.writeEntity(text, models.MessageEntity{Type: models.MessageEntityTypeTextLink, URL: url})
+	}
+
+	return b
+}
+
+// Mention links the text to the user, it works for users without username as well.
+func (b *TextBuilder) Mention(text string, userID int64) *TextBuilder {
+	if b.parseMode == "" {
+		b.writeEntity(text, models.MessageEntity{Type: models.MessageEntityTypeTextMention, User: &models.User{ID: userID}})
+		return b
+	}
+
+	return b.Link(text, fmt.Sprintf("tg://user?id=%d", userID))
+}
+
+// CustomEmoji renders the custom emoji, emoji is the fallback shown by clients which do not support it.
+func (b *TextBuilder) CustomEmoji(emoji, customEmojiID string) *TextBuilder {
+	switch b.parseMode {
+	case models.ParseModeHTML:
+		b.sb.WriteString(fmt.Sprintf(`<tg-emoji emoji-id="%s">%s</tg-emoji>`, EscapeHTML(customEmojiID), EscapeHTML(emoji)))
+	case models.ParseModeMarkdown:
+		b.sb.WriteString("![" + EscapeMarkdownV2(emoji) + "](tg://emoji?id=" + escapeChars(customEmojiID, ")\\") + ")")
+	default:
+		b.writeEntity(emoji, models.MessageEntity{Type: models.MessageEntityTypeCustomEmoji, CustomEmojiID: customEmojiID})
+	}
+
+	return b
+}
+
+func (b *TextBuilder) String() string {
+	return b.sb.String()
+}
+
+func (b *TextBuilder) ParseMode() models.ParseMode {
+	return b.parseMode
+}
+
+func (b *TextBuilder) Entities() []models.MessageEntity {
+	return b.entities
+}
+
+// MessageOptions applies parse mode and entities of the builder to the sent message.
+func (b *TextBuilder) MessageOptions() MessageOptions {
+	return func(msgCfg *bot.SendMessageParams) {
+		msgCfg.ParseMode = b.parseMode
+		msgCfg.Entities = b.entities
+	}
+}
+
+// EditMessageOptions replaces the message text with the built one.
+func (b *TextBuilder) EditMessageOptions() EditMessageOptions {
+	return func(msgCfg *bot.EditMessageTextParams) {
+		msgCfg.Text = b.String()
+		msgCfg.ParseMode = b.parseMode
+		msgCfg.Entities = b.entities
+	}
+}
+
+// MediaOptions uses the built text as media caption.
+func (b *TextBuilder) MediaOptions() MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.Caption = b.String()
+		mediaCfg.ParseMode = b.parseMode
+		mediaCfg.CaptionEntities = b.entities
+	}
+}
+
+func (b *TextBuilder) wrap(text, htmlOpen, htmlClose, mdOpen, mdClose string, entity models.MessageEntity) *TextBuilder {
+	switch b.parseMode {
+	case models.ParseModeHTML:
+		b.sb.WriteString(htmlOpen + EscapeHTML(text) + htmlClose)
+	case models.ParseModeMarkdown:
+		b.sb.WriteString(mdOpen + EscapeMarkdownV2(text) + mdClose)
+	default:
+		b.writeEntity(text, entity)
+	}
+
+	return b
+}
+
+func (b *TextBuilder) writeEntity(text string, entity models.MessageEntity) {
+	entity.Offset = b.offset
+	b.writePlain(text)
+	entity.Length = b.offset - entity.Offset
+
+	if entity.Length > 0 {
+		b.entities = append(b.entities, entity)
+	}
+}
+
+func (b *TextBuilder) writePlain(text string) {
+	b.sb.WriteString(text)
+
+	// telegram measures entity offsets in UTF-16 code units
+	for _, r := range text {
+		b.offset += utf16.RuneLen(r)
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import "testing"
+
+func TestTextBuilderEscaping(t *testing.T) {
+	html := NewHTMLBuilder().Bold("<b>").Text(" & ").Link("a", "https://x.y/?a=1&b=2").String()
+
+	if html != `<b>&lt;b&gt;</b> &amp; <a href="https://x.y/?a=1&amp;b=2">a</a>` {
+		t.Error("unexpected html: " + html)
+	}
+
+	markdown := NewMarkdownV2Builder().Italic("1.5*2").Text(" ").Code("a`b").String()
+
+	if markdown != "_1\\.5\\*2_ `a\\`b`" {
+		t.Error("unexpected markdown: " + markdown)
+	}
+}
+
+func TestTextBuilderEntities(t *testing.T) {
+	builder := NewEntitiesBuilder().Text("😀 ").Bold("bold").Text(" ").Mention("user", 42)
+
+	if builder.String() != "😀 bold user" {
+		t.Error("unexpected text: " + builder.String())
+	}
+
+	entities := builder.Entities()
+
+	if len(entities) != 2 {
+		t.Fatal("len(entities) != 2")
+	}
+
+	if entities[0].Offset != 3 || entities[0].Length != 4 {
+		t.Error("bold entity has wrong utf16 bounds")
+	}
+
+	if entities[1].Offset != 8 || entities[1].User == nil || entities[1].User.ID != 42 {
+		t.Error("mention entity is wrong")
+	}
+}
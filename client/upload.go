@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// ProgressFunc reports the amount of uploaded bytes. Total is -1 when the size of the source is unknown.
+type ProgressFunc func(uploaded, total int64)
+
+func WithMediaThumbnail(thumbnail models.InputFile) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.Thumbnail = thumbnail
+	}
+}
+
+// WithMediaProgress reports upload progress of the sent file, it has no effect for files sent by id or url.
+func WithMediaProgress(progress ProgressFunc) MediaOptions {
+	return func(mediaCfg *MediaParams) {
+		mediaCfg.Progress = progress
+	}
+}
+
+// FileFromReader uploads the file content streamed from reader.
+func FileFromReader(fileName string, reader io.Reader) models.InputFile {
+	return &models.InputFileUpload{
+		Filename: fileName,
+		Data:     reader,
+	}
+}
+
+// UploadDocument streams the document from reader and returns the message id and the file id for later reuse.
+func (t *TelegramClient) UploadDocument(ctx context.Context, recipientChatID int64, fileName string, reader io.Reader, options ...MediaOptions) (int, string, error) {
+	return t.SendDocument(ctx, recipientChatID, FileFromReader(fileName, reader), options...)
+}
+
+func (t *TelegramClient) UploadPhoto(ctx context.Context, recipientChatID int64, fileName string, reader io.Reader, options ...MediaOptions) (int, string, error) {
+	return t.SendPhoto(ctx, recipientChatID, FileFromReader(fileName, reader), options...)
+}
+
+func (t *TelegramClient) UploadVideo(ctx context.Context, recipientChatID int64, fileName string, reader io.Reader, options ...MediaOptions) (int, string, error) {
+	return t.SendVideo(ctx, recipientChatID, FileFromReader(fileName, reader), options...)
+}
+
+func (t *TelegramClient) UploadAudio(ctx context.Context, recipientChatID int64, fileName string, reader io.Reader, options ...MediaOptions) (int, string, error) {
+	return t.SendAudio(ctx, recipientChatID, FileFromReader(fileName, reader), options...)
+}
+
+func (m *MediaParams) trackProgress(file models.InputFile) models.InputFile {
+	upload, ok := file.(*models.InputFileUpload)
+
+	if !ok || m.Progress == nil {
+		return file
+	}
+
+	return &models.InputFileUpload{
+		Filename: upload.Filename,
+		Data: &progressReader{
+			reader:   upload.Data,
+			total:    readerSize(upload.Data),
+			progress: m.Progress,
+		},
+	}
+}
+
+type progressReader struct {
+	reader   io.Reader
+	uploaded int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+
+	if n > 0 {
+		p.uploaded += int64(n)
+		p.progress(p.uploaded, p.total)
+	}
+
+	return n, err
+}
+
+func readerSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+
+		offset, err := r.Seek(0, io.SeekCurrent)
+
+		if err != nil {
+			return -1
+		}
+
+		return info.Size() - offset
+	}
+
+	return -1
+}
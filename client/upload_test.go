@@ -0,0 +1,62 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestUploadDocumentProgress(t *testing.T) {
+	content := strings.Repeat("a", 64*1024)
+	var uploaded string
+
+	client, _ := newTestClient(t, func(method string, r *http.Request) any {
+		_ = r.ParseMultipartForm(1 << 20)
+
+		file, header, err := r.FormFile("document")
+
+		if err != nil {
+			t.Errorf("read uploaded document: %v", err)
+			return nil
+		}
+
+		data, _ := io.ReadAll(file)
+		uploaded = header.Filename + ":" + string(data)
+
+		return models.Message{ID: 5, Document: &models.Document{FileID: "document-id"}}
+	})
+
+	var lastUploaded, lastTotal int64
+
+	messageID, fileID, err := client.UploadDocument(context.Background(), 1, "report.txt", bytes.NewReader([]byte(content)),
+		WithMediaProgress(func(uploaded, total int64) {
+			lastUploaded, lastTotal = uploaded, total
+		}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageID != 5 || fileID != "document-id" || uploaded != "report.txt:"+content {
+		t.Fatalf("unexpected upload result %d, %s", messageID, fileID)
+	}
+
+	if lastUploaded != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Fatalf("expected progress to reach %d, got %d of %d", len(content), lastUploaded, lastTotal)
+	}
+}
+
+func TestReaderSize(t *testing.T) {
+	if size := readerSize(strings.NewReader("abc")); size != 3 {
+		t.Fatalf("expected size 3, got %d", size)
+	}
+
+	if size := readerSize(io.MultiReader(strings.NewReader("abc"))); size != -1 {
+		t.Fatalf("expected unknown size, got %d", size)
+	}
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webhookSecretHeader   = "X-Telegram-Bot-Api-Secret-Token"
+	webhookMaxBodySize    = 1 << 20
+	webhookShutdownPeriod = 5 * time.Second
+)
+
+type webhookConfig struct {
+	url        string
+	path       string
+	listenAddr string
+	secret     string
+}
+
+func newWebhookConfig(rawURL, listenAddr, secret string) (*webhookConfig, error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	path := parsed.Path
+
+	if path == "" {
+		path = "/"
+	}
+
+	return &webhookConfig{
+		url:        rawURL,
+		path:       path,
+		listenAddr: listenAddr,
+		secret:     secret,
+	}, nil
+}
+
+// IsWebhookMode reports whether the client receives updates through a webhook instead of long polling.
+func (t *TelegramClient) IsWebhookMode() bool {
+	return t.webhook != nil
+}
+
+// WebhookHandler returns the handler accepting updates pushed by telegram. Updates are delivered
+// to the same channel returned by GetUpdates, so it can be mounted on any http server.
+func (t *TelegramClient) WebhookHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if t.webhook != nil && t.webhook.secret != "" {
+			token := req.Header.Get(webhookSecretHeader)
+
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t.webhook.secret)) != 1 {
+				logrus.Warn("received webhook request with invalid secret token")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, webhookMaxBodySize))
+
+		if err != nil {
+			logrus.WithError(err).Error("failed to read webhook request body")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		update := &models.Update{}
+
+		if err = json.Unmarshal(body, update); err != nil {
+			logrus.WithError(err).Error("failed to decode webhook update")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		t.observeChatType(update)
+		t.observeBotMember(update)
+
+		select {
+		case <-req.Context().Done():
+			// telegram redelivers the update when it does not receive a successful response
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case t.updates <- update:
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (t *TelegramClient) startWebhook(ctx context.Context) {
+	_, err := t.api.SetWebhook(ctx, &bot.SetWebhookParams{
+		URL:            t.webhook.url,
+		AllowedUpdates: t.allowedUpdates,
+		SecretToken:    t.webhook.secret,
+	})
+
+	if err != nil {
+		t.updatesErr <- fmt.Errorf("register telegram webhook: %w", err)
+		return
+	}
+
+	logrus.WithField("path", t.webhook.path).Info("telegram webhook registered")
+
+	if t.webhook.listenAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(t.webhook.path, t.WebhookHandler())
+
+	server := &http.Server{
+		Addr:              t.webhook.listenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookShutdownPeriod)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logrus.WithError(err).Error("failed to shutdown webhook server")
+		}
+	}()
+
+	logrus.WithField("addr", t.webhook.listenAddr).Info("start telegram webhook server")
+
+	if err = server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		t.updatesErr <- fmt.Errorf("webhook server stopped: %w", err)
+	}
+}
+
+func (t *TelegramClient) startPolling(ctx context.Context) {
+	// telegram rejects getUpdates while a webhook is registered, e.g. after switching modes
+	if _, err := t.api.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		logrus.WithError(err).Error("failed to delete telegram webhook")
+	}
+
+	t.api.Start(ctx)
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	client := &TelegramClient{
+		updates: make(chan *models.Update, 1),
+		webhook: &webhookConfig{secret: "secret"},
+	}
+
+	handler := client.WebhookHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"update_id":1}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized without secret, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"update_id":1}`))
+	req.Header.Set(webhookSecretHeader, "secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected ok with secret, got %d", rec.Code)
+	}
+
+	update := <-client.updates
+
+	if update.ID != 1 {
+		t.Error("update.ID != 1")
+	}
+}
+
+func TestWebhookRegistrationError(t *testing.T) {
+	client, _ := newTestClient(t, func(method string, _ *http.Request) any {
+		if method == "setWebhook" {
+			return []byte(`{"ok":false,"error_code":400,"description":"Bad Request: bad webhook"}`)
+		}
+
+		return true
+	})
+
+	client.webhook = &webhookConfig{url: "https://example.com/hook", path: "/hook"}
+	client.GetUpdates(t.Context())
+
+	select {
+	case err := <-client.UpdatesErr():
+		if !strings.Contains(err.Error(), "bad webhook") {
+			t.Fatalf("expected the webhook registration error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected failed webhook registration to be reported")
+	}
+}
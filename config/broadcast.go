@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+type BroadcastConfig struct {
+	// RateShare is the part of the global telegram rate limit available to a broadcast,
+	// the rest is left for interactive traffic.
+	RateShare         float64 `env:"BROADCAST_RATE_SHARE" envDefault:"0.5"`
+	BatchSize         int     `env:"BROADCAST_BATCH_SIZE" envDefault:"500"`
+	ProgressSaveEvery int     `env:"BROADCAST_PROGRESS_SAVE_EVERY" envDefault:"50"`
+	// LeaseTimeout is the time after the last heartbeat of the running broadcast when another process
+	// may resume it.
+	LeaseTimeout time.Duration `env:"BROADCAST_LEASE_TIMEOUT" envDefault:"1m"`
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type OutboxConfig struct {
+	WorkersCount   int           `env:"OUTBOX_WORKERS_COUNT" envDefault:"4"`
+	MaxAttempts    int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10"`
+	MinBackoff     time.Duration `env:"OUTBOX_MIN_BACKOFF" envDefault:"1s"`
+	MaxBackoff     time.Duration `env:"OUTBOX_MAX_BACKOFF" envDefault:"10m"`
+	ChatLease      time.Duration `env:"OUTBOX_CHAT_LEASE" envDefault:"1m"`
+	PollInterval   time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"500ms"`
+	IdempotencyTTL time.Duration `env:"OUTBOX_IDEMPOTENCY_TTL" envDefault:"24h"`
+}
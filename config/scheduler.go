@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type SchedulerConfig struct {
+	PollInterval time.Duration `env:"SCHEDULER_POLL_INTERVAL" envDefault:"1s"`
+	BatchSize    int           `env:"SCHEDULER_BATCH_SIZE" envDefault:"100"`
+	// JobLease is how long the claimed job is hidden from other replicas, the job is run again
+	// when the replica does not complete it in time, e.g. after a crash.
+	JobLease    time.Duration `env:"SCHEDULER_JOB_LEASE" envDefault:"1m"`
+	MaxAttempts int           `env:"SCHEDULER_MAX_ATTEMPTS" envDefault:"5"`
+	RetryDelay  time.Duration `env:"SCHEDULER_RETRY_DELAY" envDefault:"30s"`
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type TelegramConfig struct {
 	AllowedUpdates       []string `env:"ALLOWED_UPDATES" envSeparator:","`
 	Token                string   `env:"BOT_TOKEN"`
@@ -7,4 +9,35 @@ type TelegramConfig struct {
 	MessagePerSecond     int      `env:"MESSAGE_PER_SECOND" envDefault:"-1"`
 	LocalizationFilePath string   `env:"LOCALIZATION_FILE_PATH"`
 	TelegramApiUrl       string   `env:"TELEGRAM_API_URL" envDefault:"https://api.telegram.org"`
+	MaxDownloadFileSize  int64    `env:"MAX_DOWNLOAD_FILE_SIZE" envDefault:"20971520"`
+
+	// WebhookURL switches the client from long polling to webhook mode when set.
+	WebhookURL string `env:"WEBHOOK_URL"`
+	// WebhookListenAddr is the address of the built-in webhook server. Leave it empty
+	// to mount TelegramClient.WebhookHandler on your own http server.
+	WebhookListenAddr string `env:"WEBHOOK_LISTEN_ADDR"`
+	WebhookSecret     string `env:"WEBHOOK_SECRET"`
+
+	// Outgoing message limits, use -1 to disable the limit of the chat type.
+	GlobalMessagesPerSecond  int `env:"GLOBAL_MESSAGES_PER_SECOND" envDefault:"25"`
+	PrivateMessagesPerMinute int `env:"PRIVATE_MESSAGES_PER_MINUTE" envDefault:"60"`
+	PrivateMessagesBurst     int `env:"PRIVATE_MESSAGES_BURST" envDefault:"2"`
+	GroupMessagesPerMinute   int `env:"GROUP_MESSAGES_PER_MINUTE" envDefault:"20"`
+	GroupMessagesBurst       int `env:"GROUP_MESSAGES_BURST" envDefault:"3"`
+	ChannelMessagesPerMinute int `env:"CHANNEL_MESSAGES_PER_MINUTE" envDefault:"20"`
+	ChannelMessagesBurst     int `env:"CHANNEL_MESSAGES_BURST" envDefault:"3"`
+
+	// PaymentProviderToken is used for invoices in provider currencies, Stars invoices do not need it.
+	PaymentProviderToken string `env:"PAYMENT_PROVIDER_TOKEN"`
+	// PaymentAnswerTimeout limits shipping and pre-checkout handlers, telegram cancels unanswered queries after 10s.
+	PaymentAnswerTimeout time.Duration `env:"PAYMENT_ANSWER_TIMEOUT" envDefault:"8s"`
+
+	// Retry policy of api requests, zero values fall back to defaults and -1 disables the setting.
+	RetryMaxAttempts int           `env:"RETRY_MAX_ATTEMPTS" envDefault:"3"`
+	RetryMinBackoff  time.Duration `env:"RETRY_MIN_BACKOFF" envDefault:"500ms"`
+	RetryMaxBackoff  time.Duration `env:"RETRY_MAX_BACKOFF" envDefault:"10s"`
+	// RetryMaxDuration limits the total time spent on retries of one api request.
+	RetryMaxDuration time.Duration `env:"RETRY_MAX_DURATION" envDefault:"1m"`
+	// RetryMaxBufferedBody is the maximum request size kept in memory to repeat it, larger uploads are not retried.
+	RetryMaxBufferedBody int64 `env:"RETRY_MAX_BUFFERED_BODY" envDefault:"10485760"`
 }
@@ -7,4 +7,31 @@ var (
 	ErrorChatNotFilled   = errors.New("chat not filled")
 	ErrorMessageNotFound = errors.New("message not found")
 	ErrorInviteIsExpired = errors.New("invite is expired")
+	ErrorFileTooLarge    = errors.New("file too large")
+
+	ErrorSessionNotFound      = errors.New("session not found")
+	ErrorSessionNotConfigured = errors.New("session storage is not configured")
+
+	ErrorBroadcastNotFound   = errors.New("broadcast not found")
+	ErrorBroadcastIsRunning  = errors.New("broadcast is running")
+	ErrorBroadcastIsFinished = errors.New("broadcast is finished")
+
+	ErrorBotBlocked            = errors.New("bot was blocked by the user")
+	ErrorUserDeactivated       = errors.New("user is deactivated")
+	ErrorChatNotFound          = errors.New("chat not found")
+	ErrorMessageNotModified    = errors.New("message is not modified")
+	ErrorMessageToEditNotFound = errors.New("message to edit not found")
+	ErrorNotEnoughRights       = errors.New("not enough rights")
+	ErrorFloodWait             = errors.New("flood wait")
+
+	ErrorInvalidInvoice    = errors.New("invalid invoice")
+	ErrorPaymentNotFound   = errors.New("payment not found")
+	ErrorPaymentIsRefunded = errors.New("payment is refunded")
+	// ErrorRefundNotSupported is returned for payments in provider currencies, they are refunded by the provider.
+	ErrorRefundNotSupported = errors.New("refund is supported only for stars payments")
+
+	ErrorJobNotFound = errors.New("job not found")
+
+	ErrorBotNotFound     = errors.New("bot not found")
+	ErrorBotAlreadyAdded = errors.New("bot already added")
 )
@@ -0,0 +1,93 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type ChatType string
+
+const (
+	ChatTypePrivate ChatType = "private"
+	ChatTypeGroup   ChatType = "group"
+	ChatTypeChannel ChatType = "channel"
+)
+
+type LimitProfile struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// ChatLimiter limits outgoing messages with a separate profile for every chat type. The type of
+// private chats and groups is derived from the chat id, channels have to be reported with SetChatType.
+type ChatLimiter struct {
+	mu       sync.RWMutex
+	limiters map[ChatType]*UserLimiter
+	channels map[int64]struct{}
+}
+
+func NewChatLimiter(profiles map[ChatType]LimitProfile) *ChatLimiter {
+	limiters := make(map[ChatType]*UserLimiter, len(profiles))
+
+	for chatType, profile := range profiles {
+		limiters[chatType] = NewUserLimiter(profile.Rate, profile.Burst)
+	}
+
+	return &ChatLimiter{
+		limiters: limiters,
+		channels: make(map[int64]struct{}),
+	}
+}
+
+func (c *ChatLimiter) Run(ctx context.Context) {
+	for _, limiter := range c.limiters {
+		go limiter.Run(ctx)
+	}
+}
+
+// SetChatType remembers the chat type, so its messages are limited with the matching profile.
+func (c *ChatLimiter) SetChatType(chatID int64, chatType ChatType) {
+	if chatID > 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if chatType == ChatTypeChannel {
+		c.channels[chatID] = struct{}{}
+		return
+	}
+
+	delete(c.channels, chatID)
+}
+
+func (c *ChatLimiter) ChatType(chatID int64) ChatType {
+	if chatID > 0 {
+		return ChatTypePrivate
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, ok := c.channels[chatID]; ok {
+		return ChatTypeChannel
+	}
+
+	return ChatTypeGroup
+}
+
+func (c *ChatLimiter) Wait(ctx context.Context, chatID int64) {
+	if limiter, ok := c.limiters[c.ChatType(chatID)]; ok {
+		limiter.Wait(ctx, chatID)
+	}
+}
+
+func (c *ChatLimiter) Backoff(chatID int64, duration time.Duration) {
+	if limiter, ok := c.limiters[c.ChatType(chatID)]; ok {
+		limiter.Backoff(chatID, duration)
+	}
+}
@@ -0,0 +1,47 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestChatLimiterProfiles(t *testing.T) {
+	chatLimiter := NewChatLimiter(map[ChatType]LimitProfile{
+		ChatTypePrivate: {Rate: rate.Inf, Burst: 1},
+		ChatTypeGroup:   {Rate: rate.Inf, Burst: 1},
+		ChatTypeChannel: {Rate: rate.Every(time.Hour), Burst: 1},
+	})
+
+	chatLimiter.SetChatType(-100, ChatTypeChannel)
+
+	if chatLimiter.ChatType(1) != ChatTypePrivate || chatLimiter.ChatType(-1) != ChatTypeGroup || chatLimiter.ChatType(-100) != ChatTypeChannel {
+		t.Fatal("unexpected chat types")
+	}
+
+	chatLimiter.Wait(context.Background(), -100)
+	chatLimiter.Wait(context.Background(), -1)
+
+	if chatLimiter.limiters[ChatTypeChannel].Check(-100) {
+		t.Fatal("expected channel limit to block the second message")
+	}
+
+	if !chatLimiter.limiters[ChatTypeGroup].Check(-1) {
+		t.Fatal("expected group limit not to block")
+	}
+}
+
+func TestUserLimiterBackoff(t *testing.T) {
+	userLimiter := NewUserLimiter(rate.Inf, 1)
+	userLimiter.Backoff(1, time.Hour)
+
+	if userLimiter.Check(1) {
+		t.Fatal("expected paused chat to be limited")
+	}
+
+	if !userLimiter.Check(2) {
+		t.Fatal("expected other chats not to be affected")
+	}
+}
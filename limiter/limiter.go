@@ -9,11 +9,12 @@ import (
 )
 
 type UserLimiter struct {
-	mu        sync.Mutex
-	limits    map[int64]*rate.Limiter
-	rate      rate.Limit
-	burst     int
-	isEnabled bool
+	mu          sync.Mutex
+	limits      map[int64]*rate.Limiter
+	pausedUntil map[int64]time.Time
+	rate        rate.Limit
+	burst       int
+	isEnabled   bool
 }
 
 func NewUserLimiter(rateLimit rate.Limit, burst int) *UserLimiter {
@@ -24,11 +25,12 @@ func NewUserLimiter(rateLimit rate.Limit, burst int) *UserLimiter {
 	}
 
 	return &UserLimiter{
-		mu:        sync.Mutex{},
-		limits:    make(map[int64]*rate.Limiter),
-		rate:      rateLimit,
-		burst:     burst,
-		isEnabled: true,
+		mu:          sync.Mutex{},
+		limits:      make(map[int64]*rate.Limiter),
+		pausedUntil: make(map[int64]time.Time),
+		rate:        rateLimit,
+		burst:       burst,
+		isEnabled:   true,
 	}
 }
 
@@ -54,6 +56,14 @@ func (u *UserLimiter) cleanup() {
 			delete(u.limits, chatID)
 		}
 	}
+
+	now := time.Now()
+
+	for chatID, until := range u.pausedUntil {
+		if until.Before(now) {
+			delete(u.pausedUntil, chatID)
+		}
+	}
 }
 
 func (u *UserLimiter) Wait(ctx context.Context, userID int64) {
@@ -62,13 +72,25 @@ func (u *UserLimiter) Wait(ctx context.Context, userID int64) {
 	}
 
 	u.mu.Lock()
-	defer u.mu.Unlock()
-
 	limiter, ok := u.limits[userID]
 	if !ok {
 		limiter = rate.NewLimiter(u.rate, u.burst)
 		u.limits[userID] = limiter
 	}
+	pausedUntil := u.pausedUntil[userID]
+	u.mu.Unlock()
+
+	if wait := time.Until(pausedUntil); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			logrus.WithError(ctx.Err()).Error("error wait limiter")
+			return
+		case <-timer.C:
+		}
+	}
 
 	if err := limiter.Wait(ctx); err != nil {
 		logrus.WithError(err).Error("error wait limiter")
@@ -77,6 +99,22 @@ func (u *UserLimiter) Wait(ctx context.Context, userID int64) {
 	return
 }
 
+// Backoff pauses the user for the given duration, e.g. when telegram responds with retry_after for the chat.
+func (u *UserLimiter) Backoff(userID int64, duration time.Duration) {
+	if !u.isEnabled {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	until := time.Now().Add(duration)
+
+	if until.After(u.pausedUntil[userID]) {
+		u.pausedUntil[userID] = until
+	}
+}
+
 func (u *UserLimiter) Check(userID int64) bool {
 	if !u.isEnabled {
 		return true
@@ -85,6 +123,10 @@ func (u *UserLimiter) Check(userID int64) bool {
 	u.mu.Lock()
 	defer u.mu.Unlock()
 
+	if time.Now().Before(u.pausedUntil[userID]) {
+		return false
+	}
+
 	limiter, ok := u.limits[userID]
 
 	if !ok {
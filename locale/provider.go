@@ -76,3 +76,15 @@ func (l *LocalizationProvider) GetWithCulture(culture, key string, args ...any)
 
 	return content
 }
+
+// HasLocalization reports whether the text is the localization of the key in any culture,
+// e.g. to recognize the text of reply keyboard buttons.
+func (l *LocalizationProvider) HasLocalization(key, text string) bool {
+	for _, content := range l.locales.LocalizedContent[key] {
+		if content == text {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/locale"
+	"github.com/nejkit/telegram-bot-core/v2/state"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// HandlerSet registers handlers on the state service of the bot. Bots added with the same set share
+// the handlers, handlers reply with the client returned by state.BotFromContext and change actions through
+// the storage returned by storage.ActionStorageFromContext.
+type HandlerSet[Action storage.UserAction, Command state.BotCommand, Callback state.CallbackPrefix] func(service *state.TelegramStateService[Action, Command, Callback])
+
+type managedBot[Action storage.UserAction, Command state.BotCommand, Callback state.CallbackPrefix] struct {
+	name    string
+	client  *client.TelegramClient
+	service *state.TelegramStateService[Action, Command, Callback]
+	cancel  context.CancelFunc
+}
+
+// BotManager hosts several bots in one process. Every bot has its own client, limiters and storage prefix,
+// the bots share the worker pool and the redis client.
+type BotManager[Action storage.UserAction, Command state.BotCommand, Callback state.CallbackPrefix] struct {
+	cfg         config.TelegramConfig
+	redisClient *redis.Client
+	locales     *locale.LocalizationProvider
+	pool        *state.WorkerPool
+
+	mu   sync.Mutex
+	ctx  context.Context
+	bots map[string]*managedBot[Action, Command, Callback]
+}
+
+// NewBotManager creates the manager, cfg is the base config of the bots, its token is replaced by the token
+// of the added bot and WorkersCount sets the size of the shared worker pool. The bots receive updates by long
+// polling, webhook settings of cfg are ignored.
+func NewBotManager[Action storage.UserAction, Command state.BotCommand, Callback state.CallbackPrefix](
+	cfg config.TelegramConfig,
+	redisClient *redis.Client,
+	locales *locale.LocalizationProvider,
+) *BotManager[Action, Command, Callback] {
+	return &BotManager[Action, Command, Callback]{
+		cfg:         cfg,
+		redisClient: redisClient,
+		locales:     locales,
+		pool:        state.NewWorkerPool(cfg.WorkersCount),
+		bots:        make(map[string]*managedBot[Action, Command, Callback]),
+	}
+}
+
+// AddBot creates the bot with its own storage prefix, the bot starts at once when the manager is running.
+// The client is created without holding the lock, so other bots are managed while getMe of the new one is requested.
+func (m *BotManager[Action, Command, Callback]) AddBot(token, botInstancePrefix string, handlers HandlerSet[Action, Command, Callback]) error {
+	if m.hasBot(token) {
+		return domain.ErrorBotAlreadyAdded
+	}
+
+	cfg := m.cfg
+	cfg.Token = token
+	cfg.WebhookURL = ""
+	cfg.WebhookListenAddr = ""
+
+	telegramClient, err := client.CreateTelegramClient(&cfg)
+
+	if err != nil {
+		return err
+	}
+
+	service := state.NewTelegramStateService[Action, Command, Callback](
+		cfg,
+		storage.NewRedisUserActionStorage[int](botInstancePrefix, m.redisClient),
+		storage.NewRedisUserMessageStorage(botInstancePrefix, m.redisClient),
+		telegramClient,
+		m.locales,
+	).UseWorkerPool(m.pool).
+		UseSessionStorage(storage.NewRedisSessionStorage(botInstancePrefix, m.redisClient, 0)).
+		SetBotName(botInstancePrefix)
+
+	if handlers != nil {
+		handlers(service)
+	}
+
+	bot := &managedBot[Action, Command, Callback]{
+		name:    botInstancePrefix,
+		client:  telegramClient,
+		service: service,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the same token may be added concurrently while the client was created
+	if _, ok := m.bots[token]; ok {
+		return domain.ErrorBotAlreadyAdded
+	}
+
+	m.bots[token] = bot
+
+	if m.ctx != nil {
+		m.startBot(bot)
+	}
+
+	return nil
+}
+
+func (m *BotManager[Action, Command, Callback]) hasBot(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.bots[token]
+
+	return ok
+}
+
+// RemoveBot stops receiving updates of the bot, handlers already running are not interrupted.
+func (m *BotManager[Action, Command, Callback]) RemoveBot(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bot, ok := m.bots[token]
+
+	if !ok {
+		return domain.ErrorBotNotFound
+	}
+
+	if bot.cancel != nil {
+		bot.cancel()
+	}
+
+	delete(m.bots, token)
+
+	logrus.WithField("bot", bot.name).Info("bot removed")
+
+	return nil
+}
+
+func (m *BotManager[Action, Command, Callback]) GetClient(token string) (*client.TelegramClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bot, ok := m.bots[token]
+
+	if !ok {
+		return nil, domain.ErrorBotNotFound
+	}
+
+	return bot.client, nil
+}
+
+// Run starts the worker pool and the added bots, it blocks until the context is done.
+func (m *BotManager[Action, Command, Callback]) Run(ctx context.Context) {
+	m.pool.Run(ctx)
+
+	m.mu.Lock()
+	m.ctx = ctx
+
+	for _, bot := range m.bots {
+		m.startBot(bot)
+	}
+	m.mu.Unlock()
+
+	<-ctx.Done()
+}
+
+func (m *BotManager[Action, Command, Callback]) startBot(bot *managedBot[Action, Command, Callback]) {
+	var ctx context.Context
+
+	ctx, bot.cancel = context.WithCancel(m.ctx)
+
+	logrus.WithField("bot", bot.name).Info("start bot")
+
+	go bot.service.Run(ctx)
+}
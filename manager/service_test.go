@@ -0,0 +1,209 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/state"
+	"github.com/redis/go-redis/v9"
+)
+
+const startUpdate = `[{"update_id":1,"message":{"message_id":1,"chat":{"id":10,"type":"private"},"from":{"id":10,"first_name":"user"},
+"text":"/start","entities":[{"type":"bot_command","offset":0,"length":6}]}}]`
+
+// fakeBotApi serves several bots, every bot receives the /start command once.
+type fakeBotApi struct {
+	mu    sync.Mutex
+	polls map[string]int
+}
+
+func (f *fakeBotApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(path.Dir(r.URL.Path), "/bot")
+	method := path.Base(r.URL.Path)
+
+	var result any = true
+
+	switch method {
+	case "getMe":
+		result = models.User{ID: 1, IsBot: true, Username: token}
+	case "getUpdates":
+		f.mu.Lock()
+		f.polls[token]++
+		first := f.polls[token] == 1
+		f.mu.Unlock()
+
+		if first {
+			result = json.RawMessage(startUpdate)
+		} else {
+			time.Sleep(20 * time.Millisecond)
+			result = []models.Update{}
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func (f *fakeBotApi) pollCount(token string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.polls[token]
+}
+
+func TestBotManagerLifecycle(t *testing.T) {
+	api := &fakeBotApi{polls: make(map[string]int)}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	manager := NewBotManager[int, string, string](config.TelegramConfig{
+		TelegramApiUrl:   server.URL,
+		WorkersCount:     2,
+		MessagePerSecond: -1,
+	}, redis.NewClient(&redis.Options{}), nil)
+
+	started := make(chan string, 2)
+
+	handlers := func(service *state.TelegramStateService[int, string, string]) {
+		service.RegisterCommandHandler("start", func(ctx context.Context, _ *models.Update) error {
+			started <- state.BotFromContext(ctx).Name
+			return nil
+		})
+	}
+
+	if err := manager.AddBot("first", "first-bot", handlers); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.AddBot("first", "first-bot", handlers); !errors.Is(err, domain.ErrorBotAlreadyAdded) {
+		t.Fatalf("expected bot already added, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go manager.Run(ctx)
+
+	// the bot added to the running manager starts at once
+	if err := manager.AddBot("second", "second-bot", handlers); err != nil {
+		t.Fatal(err)
+	}
+
+	names := make(map[string]bool)
+
+	for range 2 {
+		select {
+		case name := <-started:
+			names[name] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected both bots to handle updates, got %v", names)
+		}
+	}
+
+	if !names["first-bot"] || !names["second-bot"] {
+		t.Fatalf("expected updates handled by both bots, got %v", names)
+	}
+
+	if err := manager.RemoveBot("first"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.GetClient("first"); !errors.Is(err, domain.ErrorBotNotFound) {
+		t.Fatalf("expected removed bot to be not found, got %v", err)
+	}
+
+	if _, err := manager.GetClient("second"); err != nil {
+		t.Fatalf("expected second bot to keep running, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	polls := api.pollCount("first")
+	time.Sleep(200 * time.Millisecond)
+
+	if api.pollCount("first") != polls {
+		t.Fatal("expected removed bot to stop polling")
+	}
+
+	if err := manager.RemoveBot("first"); !errors.Is(err, domain.ErrorBotNotFound) {
+		t.Fatalf("expected bot not found, got %v", err)
+	}
+}
+
+func TestAddBotDoesNotBlockOtherBots(t *testing.T) {
+	api := &fakeBotApi{polls: make(map[string]int)}
+	pending := make(chan struct{}, 2)
+	release := make(chan struct{})
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/botslow/") {
+			pending <- struct{}{}
+			<-release
+		}
+
+		api.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	defer releaseOnce()
+
+	manager := NewBotManager[int, string, string](config.TelegramConfig{
+		TelegramApiUrl:   server.URL,
+		WorkersCount:     1,
+		MessagePerSecond: -1,
+	}, redis.NewClient(&redis.Options{}), nil)
+
+	results := make(chan error, 2)
+
+	for range 2 {
+		go func() {
+			results <- manager.AddBot("slow", "slow-bot", nil)
+		}()
+	}
+
+	<-pending
+
+	// getMe of the slow bot is pending, adding another bot must not wait for it
+	added := make(chan error, 1)
+
+	go func() {
+		added <- manager.AddBot("fast", "fast-bot", nil)
+	}()
+
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected bot added while the client of another bot is created")
+	}
+
+	releaseOnce()
+
+	var succeeded, duplicates int
+
+	for range 2 {
+		switch err := <-results; {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, domain.ErrorBotAlreadyAdded):
+			duplicates++
+		default:
+			t.Fatal(err)
+		}
+	}
+
+	if succeeded != 1 || duplicates != 1 {
+		t.Fatalf("expected the bot added concurrently once, got %d added and %d duplicates", succeeded, duplicates)
+	}
+}
@@ -0,0 +1,370 @@
+package outbox
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxAttempts  = 10
+	defaultMinBackoff   = time.Second
+	defaultMaxBackoff   = 10 * time.Minute
+	defaultChatLease    = time.Minute
+	defaultPollInterval = 500 * time.Millisecond
+)
+
+var errUnknownMessageType = errors.New("unknown outbox message type")
+
+type DeliveredHandlerFunc func(ctx context.Context, message *storage.OutboxMessage, telegramMessageID int)
+
+// OutboxService persists outgoing messages and delivers them in order per chat, retrying failed
+// deliveries with exponential backoff. Messages failing permanently are moved to the dead letters list.
+type OutboxService struct {
+	cfg            config.OutboxConfig
+	storage        storage.OutboxStorage
+	telegramClient *client.TelegramClient
+
+	deliveredHandler DeliveredHandlerFunc
+}
+
+func NewOutboxService(
+	cfg config.OutboxConfig,
+	outboxStorage storage.OutboxStorage,
+	telegramClient *client.TelegramClient,
+) *OutboxService {
+	return &OutboxService{
+		cfg:            withDefaults(cfg),
+		storage:        outboxStorage,
+		telegramClient: telegramClient,
+	}
+}
+
+// withDefaults replaces zero values of the config with defaults.
+func withDefaults(cfg config.OutboxConfig) config.OutboxConfig {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = max(defaultMaxBackoff, cfg.MinBackoff)
+	}
+
+	if cfg.ChatLease <= 0 {
+		cfg.ChatLease = defaultChatLease
+	}
+
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+
+	return cfg
+}
+
+// RegisterDeliveredHandler sets the handler called after successful delivery, e.g. to track the sent message.
+func (s *OutboxService) RegisterDeliveredHandler(handler DeliveredHandlerFunc) *OutboxService {
+	s.deliveredHandler = handler
+
+	return s
+}
+
+// Send queues the message. Repeated calls with the same idempotency key are ignored and return false.
+// Empty key is replaced with a random one.
+func (s *OutboxService) Send(ctx context.Context, idempotencyKey string, chatID int64, text string, options ...client.MessageOptions) (bool, error) {
+	cfg := &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	message := s.newMessage(idempotencyKey, storage.OutboxMessageSend, chatID)
+	message.Text = cfg.Text
+	message.ParseMode = cfg.ParseMode
+	message.Entities = cfg.Entities
+	message.ThreadID = cfg.MessageThreadID
+
+	switch markup := cfg.ReplyMarkup.(type) {
+	case models.InlineKeyboardMarkup:
+		message.InlineKeyboard = &markup
+	case models.ReplyKeyboardMarkup:
+		message.ReplyKeyboard = &markup
+	case models.ReplyKeyboardRemove:
+		message.RemoveKeyboard = true
+	}
+
+	return s.storage.Enqueue(ctx, message)
+}
+
+// Edit queues text edit of already sent message, it is delivered in order with other messages of the chat.
+func (s *OutboxService) Edit(ctx context.Context, idempotencyKey string, chatID int64, messageID int, options ...client.EditMessageOptions) (bool, error) {
+	cfg := &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	message := s.newMessage(idempotencyKey, storage.OutboxMessageEdit, chatID)
+	message.MessageID = messageID
+	message.Text = cfg.Text
+	message.ParseMode = cfg.ParseMode
+	message.Entities = cfg.Entities
+
+	if markup, ok := cfg.ReplyMarkup.(models.InlineKeyboardMarkup); ok {
+		message.InlineKeyboard = &markup
+	}
+
+	return s.storage.Enqueue(ctx, message)
+}
+
+func (s *OutboxService) GetDeadLetters(ctx context.Context, limit int) ([]storage.OutboxMessage, error) {
+	return s.storage.GetDeadLetters(ctx, limit)
+}
+
+func (s *OutboxService) DeleteDeadLetters(ctx context.Context) error {
+	return s.storage.DeleteDeadLetters(ctx)
+}
+
+func (s *OutboxService) Run(ctx context.Context) {
+	logrus.WithField("workersCount", s.cfg.WorkersCount).Info("start outbox delivery workers")
+
+	for i := range max(s.cfg.WorkersCount, 1) {
+		go s.runWorker(ctx, i)
+	}
+}
+
+func (s *OutboxService) runWorker(ctx context.Context, workerID int) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if s.processNext(ctx, logrus.WithField("workerID", workerID)) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *OutboxService) processNext(ctx context.Context, log *logrus.Entry) bool {
+	chatID, ok, err := s.storage.ClaimChat(ctx, s.cfg.ChatLease)
+
+	if err != nil {
+		log.WithError(err).Error("failed to claim outbox chat")
+		return false
+	}
+
+	if !ok {
+		return false
+	}
+
+	log = log.WithField("chatID", chatID)
+
+	message, err := s.storage.PeekMessage(ctx, chatID)
+
+	if err != nil {
+		// the chat lease expires and the message is retried later
+		log.WithError(err).Error("failed to get outbox message")
+		return false
+	}
+
+	if message == nil {
+		if err = s.storage.CompleteMessage(ctx, &storage.OutboxMessage{ChatID: chatID}); err != nil {
+			log.WithError(err).Error("failed to release empty outbox chat")
+		}
+
+		return true
+	}
+
+	log = log.WithField("outboxMessageID", message.ID)
+
+	done := make(chan struct{})
+	go s.keepChatLease(ctx, chatID, done, log)
+
+	telegramMessageID, err := s.deliver(ctx, message)
+	close(done)
+
+	if err == nil {
+		log.Debug("outbox message delivered")
+
+		if err = s.storage.CompleteMessage(ctx, message); err != nil {
+			log.WithError(err).Error("failed to complete outbox message")
+		}
+
+		if s.deliveredHandler != nil {
+			s.deliveredHandler(ctx, message, telegramMessageID)
+		}
+
+		return true
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	message.Attempts++
+	message.LastError = err.Error()
+
+	if isPermanentError(err) || message.Attempts >= s.cfg.MaxAttempts {
+		log.WithError(err).Error("outbox message delivery failed, move to dead letters")
+
+		if err = s.storage.DeadLetter(ctx, message); err != nil {
+			log.WithError(err).Error("failed to move outbox message to dead letters")
+		}
+
+		return true
+	}
+
+	message.NextAttemptAt = time.Now().Add(s.retryDelay(message.Attempts, err))
+
+	log.WithError(err).WithField("nextAttemptAt", message.NextAttemptAt).Warn("outbox message delivery failed, retry later")
+
+	if err = s.storage.RetryMessage(ctx, message); err != nil {
+		log.WithError(err).Error("failed to reschedule outbox message")
+	}
+
+	return true
+}
+
+// keepChatLease extends the lease of the chat until the delivery is done. A send slowed down by retries and
+// limits must not let another worker claim the chat and deliver its messages twice or out of order.
+func (s *OutboxService) keepChatLease(ctx context.Context, chatID int64, done <-chan struct{}, log *logrus.Entry) {
+	ticker := time.NewTicker(s.cfg.ChatLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := s.storage.ExtendChatLease(ctx, chatID, s.cfg.ChatLease); err != nil {
+				log.WithError(err).Error("failed to extend outbox chat lease")
+			}
+		}
+	}
+}
+
+func (s *OutboxService) deliver(ctx context.Context, message *storage.OutboxMessage) (int, error) {
+	// errors ignored by the client, e.g. blocked users, must not be reported as delivered
+	ctx = client.WithIgnoredErrors(ctx)
+
+	switch message.Type {
+	case storage.OutboxMessageSend:
+		options := []client.MessageOptions{
+			client.WithSendParseMode(message.ParseMode),
+			client.WithSendEntities(message.Entities),
+			client.WithSendThread(message.ThreadID),
+		}
+
+		switch {
+		case message.InlineKeyboard != nil:
+			options = append(options, client.WithSendInlineKeyboard(*message.InlineKeyboard))
+		case message.ReplyKeyboard != nil:
+			options = append(options, client.WithSendReplyKeyboard(*message.ReplyKeyboard))
+		case message.RemoveKeyboard:
+			options = append(options, client.WithRemoveReplyKeyboard())
+		}
+
+		return s.telegramClient.SendMessage(ctx, message.ChatID, message.Text, options...)
+
+	case storage.OutboxMessageEdit:
+		options := []client.EditMessageOptions{
+			client.WithEditMessageText(message.Text),
+			client.WithEditParseMode(message.ParseMode),
+			client.WithEditEntities(message.Entities),
+		}
+
+		if message.InlineKeyboard != nil {
+			options = append(options, client.WithEditInlineKeyboard(*message.InlineKeyboard))
+		}
+
+		err := s.telegramClient.EditMessage(ctx, message.ChatID, message.MessageID, options...)
+
+		// the message already has the requested content, e.g. the previous attempt succeeded
+		if errors.Is(err, domain.ErrorMessageNotModified) {
+			err = nil
+		}
+
+		return message.MessageID, err
+	}
+
+	return 0, errUnknownMessageType
+}
+
+func (s *OutboxService) retryDelay(attempts int, err error) time.Duration {
+	var floodWait *client.FloodWaitError
+
+	if errors.As(err, &floodWait) && floodWait.RetryAfter > 0 {
+		return floodWait.RetryAfter
+	}
+
+	delay := s.cfg.MinBackoff << min(attempts-1, 30)
+
+	if delay <= 0 || delay > s.cfg.MaxBackoff {
+		delay = s.cfg.MaxBackoff
+	}
+
+	// full jitter on the upper half spreads retries of many chats failed at once
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (s *OutboxService) newMessage(idempotencyKey string, messageType storage.OutboxMessageType, chatID int64) *storage.OutboxMessage {
+	if idempotencyKey == "" {
+		idempotencyKey = randomKey()
+	}
+
+	now := time.Now()
+
+	return &storage.OutboxMessage{
+		ID:            idempotencyKey,
+		Type:          messageType,
+		ChatID:        chatID,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+func isPermanentError(err error) bool {
+	return errors.Is(err, errUnknownMessageType) ||
+		errors.Is(err, bot.ErrorBadRequest) ||
+		errors.Is(err, bot.ErrorForbidden) ||
+		errors.Is(err, bot.ErrorUnauthorized) ||
+		errors.Is(err, bot.ErrorNotFound)
+}
+
+func randomKey() string {
+	buf := make([]byte, 16)
+	_, _ = cryptorand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,172 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeBotApi fails sendMessage with the queued failures of the text before succeeding.
+type fakeBotApi struct {
+	mu       sync.Mutex
+	failures map[string][]string
+	sent     []string
+}
+
+func (f *fakeBotApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	response := map[string]any{"ok": true, "result": true}
+
+	switch path.Base(r.URL.Path) {
+	case "getMe":
+		response["result"] = models.User{ID: 1, IsBot: true}
+	case "sendMessage":
+		_ = r.ParseMultipartForm(1 << 20)
+		text := r.FormValue("text")
+
+		f.mu.Lock()
+
+		if failures := f.failures[text]; len(failures) > 0 {
+			f.failures[text] = failures[1:]
+			response = map[string]any{"ok": false, "error_code": 500, "description": "Internal Server Error"}
+
+			if failures[0] == "blocked" {
+				response = map[string]any{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}
+			}
+		} else {
+			f.sent = append(f.sent, text)
+			response["result"] = models.Message{ID: len(f.sent)}
+		}
+
+		f.mu.Unlock()
+	}
+
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeBotApi) sentTexts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.sent...)
+}
+
+func newTestService(t *testing.T, failures map[string][]string) (*OutboxService, *fakeBotApi, *storage.InMemoryOutboxStorage) {
+	api := &fakeBotApi{failures: failures}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	telegramClient, err := client.CreateTelegramClient(&config.TelegramConfig{
+		Token:                    "test",
+		TelegramApiUrl:           server.URL,
+		GlobalMessagesPerSecond:  -1,
+		PrivateMessagesPerMinute: -1,
+		RetryMaxAttempts:         -1,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outboxStorage := storage.NewInMemoryOutboxStorage(time.Hour)
+
+	service := NewOutboxService(config.OutboxConfig{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  2 * time.Millisecond,
+	}, outboxStorage, telegramClient)
+
+	return service, api, outboxStorage
+}
+
+// processAll delivers queued messages until no chat is due for the timeout.
+func processAll(service *OutboxService) {
+	log := logrus.NewEntry(logrus.StandardLogger())
+	deadline := time.Now().Add(200 * time.Millisecond)
+
+	for time.Now().Before(deadline) {
+		if !service.processNext(context.Background(), log) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestOutboxRetriesInOrder(t *testing.T) {
+	service, api, _ := newTestService(t, map[string][]string{"first": {"unavailable", "unavailable"}})
+
+	var delivered []string
+
+	service.RegisterDeliveredHandler(func(_ context.Context, message *storage.OutboxMessage, telegramMessageID int) {
+		delivered = append(delivered, message.Text)
+	})
+
+	ctx := context.Background()
+
+	for _, text := range []string{"first", "second", "third"} {
+		_, _ = service.Send(ctx, text, 1, text)
+	}
+
+	if ok, _ := service.Send(ctx, "first", 1, "first"); ok {
+		t.Fatal("expected repeated idempotency key to be ignored")
+	}
+
+	processAll(service)
+
+	expected := []string{"first", "second", "third"}
+
+	if !slices.Equal(api.sentTexts(), expected) || !slices.Equal(delivered, expected) {
+		t.Fatalf("expected retried messages delivered in order, got %v", api.sentTexts())
+	}
+}
+
+func TestOutboxDeadLetters(t *testing.T) {
+	service, api, _ := newTestService(t, map[string][]string{
+		"blocked":     {"blocked"},
+		"unavailable": {"unavailable", "unavailable", "unavailable"},
+	})
+
+	delivered := 0
+
+	service.RegisterDeliveredHandler(func(context.Context, *storage.OutboxMessage, int) {
+		delivered++
+	})
+
+	ctx := context.Background()
+
+	_, _ = service.Send(ctx, "blocked", 1, "blocked")
+	_, _ = service.Send(ctx, "unavailable", 2, "unavailable")
+	_, _ = service.Send(ctx, "next", 1, "next")
+
+	processAll(service)
+
+	deadLetters, _ := service.GetDeadLetters(ctx, 10)
+
+	if len(deadLetters) != 2 || delivered != 1 || !slices.Equal(api.sentTexts(), []string{"next"}) {
+		t.Fatalf("expected blocked and failed messages in dead letters, got %v, delivered %d", deadLetters, delivered)
+	}
+
+	for _, message := range deadLetters {
+		if message.Text == "unavailable" && message.Attempts != 3 {
+			t.Fatalf("expected message dead lettered after max attempts, got %d", message.Attempts)
+		}
+	}
+}
+
+func TestOutboxConfigDefaults(t *testing.T) {
+	cfg := withDefaults(config.OutboxConfig{})
+
+	if cfg.PollInterval <= 0 || cfg.ChatLease <= 0 || cfg.MaxAttempts <= 0 || cfg.MinBackoff <= 0 || cfg.MaxBackoff < cfg.MinBackoff {
+		t.Fatalf("expected defaults for zero config, got %+v", cfg)
+	}
+}
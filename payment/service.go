@@ -0,0 +1,124 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+)
+
+var errNotPaymentUpdate = errors.New("update does not contain payment")
+
+// PaymentService keeps records of received payments and refunds Stars payments.
+type PaymentService struct {
+	storage        storage.PaymentStorage
+	telegramClient *client.TelegramClient
+}
+
+func NewPaymentService(
+	paymentStorage storage.PaymentStorage,
+	telegramClient *client.TelegramClient,
+) *PaymentService {
+	return &PaymentService{
+		storage:        paymentStorage,
+		telegramClient: telegramClient,
+	}
+}
+
+// RecordPayment saves the payment from the successful_payment message, it is meant to be called
+// from the handler registered with RegisterSuccessfulPaymentHandler.
+func (s *PaymentService) RecordPayment(ctx context.Context, update *models.Update) (*storage.PaymentInfo, error) {
+	if update.Message == nil || update.Message.SuccessfulPayment == nil {
+		return nil, errNotPaymentUpdate
+	}
+
+	successfulPayment := update.Message.SuccessfulPayment
+
+	payment := &storage.PaymentInfo{
+		ChargeID:         successfulPayment.TelegramPaymentChargeID,
+		ProviderChargeID: successfulPayment.ProviderPaymentChargeID,
+		ChatID:           update.Message.Chat.ID,
+		Currency:         successfulPayment.Currency,
+		TotalAmount:      successfulPayment.TotalAmount,
+		Payload:          successfulPayment.InvoicePayload,
+		ShippingOptionID: successfulPayment.ShippingOptionID,
+		IsRecurring:      successfulPayment.IsRecurring,
+		Status:           storage.PaymentStatusPaid,
+		CreatedAt:        time.Unix(int64(update.Message.Date), 0),
+	}
+
+	if update.Message.From != nil {
+		payment.UserID = update.Message.From.ID
+	}
+
+	if successfulPayment.SubscriptionExpirationDate > 0 {
+		payment.ExpiresAt = time.Unix(int64(successfulPayment.SubscriptionExpirationDate), 0)
+	}
+
+	if err := s.storage.SavePayment(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// RecordRefund marks the payment from the refunded_payment message as refunded.
+func (s *PaymentService) RecordRefund(ctx context.Context, update *models.Update) (*storage.PaymentInfo, error) {
+	if update.Message == nil || update.Message.RefundedPayment == nil {
+		return nil, errNotPaymentUpdate
+	}
+
+	payment, err := s.storage.GetPayment(ctx, update.Message.RefundedPayment.TelegramPaymentChargeID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, s.markRefunded(ctx, payment)
+}
+
+// Refund returns the Stars to the user and marks the payment as refunded.
+func (s *PaymentService) Refund(ctx context.Context, chargeID string) error {
+	payment, err := s.storage.GetPayment(ctx, chargeID)
+
+	if err != nil {
+		return err
+	}
+
+	if payment.Status == storage.PaymentStatusRefunded {
+		return domain.ErrorPaymentIsRefunded
+	}
+
+	if payment.Currency != client.CurrencyStars {
+		return domain.ErrorRefundNotSupported
+	}
+
+	if err = s.telegramClient.RefundStarPayment(client.WithIgnoredErrors(ctx), payment.UserID, payment.ChargeID); err != nil {
+		return err
+	}
+
+	return s.markRefunded(ctx, payment)
+}
+
+func (s *PaymentService) GetPayment(ctx context.Context, chargeID string) (*storage.PaymentInfo, error) {
+	return s.storage.GetPayment(ctx, chargeID)
+}
+
+func (s *PaymentService) GetUserPayments(ctx context.Context, userID int64) ([]storage.PaymentInfo, error) {
+	return s.storage.GetUserPayments(ctx, userID)
+}
+
+func (s *PaymentService) markRefunded(ctx context.Context, payment *storage.PaymentInfo) error {
+	if payment.Status == storage.PaymentStatusRefunded {
+		return nil
+	}
+
+	payment.Status = storage.PaymentStatusRefunded
+	payment.RefundedAt = time.Now()
+
+	return s.storage.SavePayment(ctx, payment)
+}
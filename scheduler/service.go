@@ -0,0 +1,429 @@
+package scheduler
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultJobLease     = time.Minute
+	defaultMaxAttempts  = 5
+	defaultRetryDelay   = 30 * time.Second
+)
+
+var errUnknownJobType = errors.New("unknown scheduled job type")
+
+// JobHandlerFunc runs the scheduled handler job, the job contains chat id and payload passed on scheduling.
+type JobHandlerFunc func(ctx context.Context, job *storage.ScheduledJob) error
+
+// SchedulerService persists jobs and runs them when they are due. Due jobs are leased by one replica,
+// so several replicas sharing the storage do not run the same job twice.
+type SchedulerService struct {
+	cfg            config.SchedulerConfig
+	storage        storage.SchedulerStorage
+	telegramClient *client.TelegramClient
+
+	handlers map[string]JobHandlerFunc
+}
+
+func NewSchedulerService(
+	cfg config.SchedulerConfig,
+	schedulerStorage storage.SchedulerStorage,
+	telegramClient *client.TelegramClient,
+) *SchedulerService {
+	return &SchedulerService{
+		cfg:            withDefaults(cfg),
+		storage:        schedulerStorage,
+		telegramClient: telegramClient,
+		handlers:       make(map[string]JobHandlerFunc),
+	}
+}
+
+// withDefaults replaces zero values of the config with defaults.
+func withDefaults(cfg config.SchedulerConfig) config.SchedulerConfig {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+
+	if cfg.JobLease <= 0 {
+		cfg.JobLease = defaultJobLease
+	}
+
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultRetryDelay
+	}
+
+	return cfg
+}
+
+// RegisterJobHandler registers the handler invoked by jobs scheduled with ScheduleHandler. Handlers have to be
+// registered before Run on every replica, jobs survive restarts and may be run by any of them.
+func (s *SchedulerService) RegisterJobHandler(name string, handler JobHandlerFunc) *SchedulerService {
+	s.handlers[name] = handler
+
+	return s
+}
+
+// ScheduleSend schedules sending of the message. Scheduling the job with the id of the existing job replaces it,
+// empty id is replaced with a random one. The id of the job is returned to cancel it.
+func (s *SchedulerService) ScheduleSend(ctx context.Context, jobID string, runAt time.Time, chatID int64, text string, options ...client.MessageOptions) (string, error) {
+	cfg := &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	job := newJob(jobID, storage.ScheduledJobSend, runAt, chatID)
+	job.Text = cfg.Text
+	job.ParseMode = cfg.ParseMode
+	job.Entities = cfg.Entities
+	job.ThreadID = cfg.MessageThreadID
+
+	if markup, ok := cfg.ReplyMarkup.(models.InlineKeyboardMarkup); ok {
+		job.InlineKeyboard = &markup
+	}
+
+	return job.ID, s.storage.ScheduleJob(ctx, job)
+}
+
+func (s *SchedulerService) ScheduleEdit(ctx context.Context, jobID string, runAt time.Time, chatID int64, messageID int, options ...client.EditMessageOptions) (string, error) {
+	cfg := &bot.EditMessageTextParams{
+		ChatID:    chatID,
+		MessageID: messageID,
+	}
+
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	job := newJob(jobID, storage.ScheduledJobEdit, runAt, chatID)
+	job.MessageID = messageID
+	job.Text = cfg.Text
+	job.ParseMode = cfg.ParseMode
+	job.Entities = cfg.Entities
+
+	if markup, ok := cfg.ReplyMarkup.(models.InlineKeyboardMarkup); ok {
+		job.InlineKeyboard = &markup
+	}
+
+	return job.ID, s.storage.ScheduleJob(ctx, job)
+}
+
+func (s *SchedulerService) ScheduleDelete(ctx context.Context, jobID string, runAt time.Time, chatID int64, messageID int) (string, error) {
+	job := newJob(jobID, storage.ScheduledJobDelete, runAt, chatID)
+	job.MessageID = messageID
+
+	return job.ID, s.storage.ScheduleJob(ctx, job)
+}
+
+// ScheduleHandler schedules invocation of the handler registered with RegisterJobHandler.
+func (s *SchedulerService) ScheduleHandler(ctx context.Context, jobID string, runAt time.Time, chatID int64, handlerName, payload string) (string, error) {
+	job := newJob(jobID, storage.ScheduledJobHandler, runAt, chatID)
+	job.HandlerName = handlerName
+	job.Payload = payload
+
+	return job.ID, s.storage.ScheduleJob(ctx, job)
+}
+
+// Cancel removes the job. The job being run at the moment is not interrupted, but it is not retried after a failure.
+func (s *SchedulerService) Cancel(ctx context.Context, jobID string) error {
+	return s.storage.CancelJob(ctx, jobID)
+}
+
+func (s *SchedulerService) GetJob(ctx context.Context, jobID string) (*storage.ScheduledJob, error) {
+	return s.storage.GetJob(ctx, jobID)
+}
+
+func (s *SchedulerService) Run(ctx context.Context) {
+	logrus.Info("start scheduler")
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runDueJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SchedulerService) runDueJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimedAt := time.Now()
+		jobs, err := s.storage.ClaimDueJobs(ctx, s.cfg.JobLease, s.cfg.BatchSize)
+
+		if err != nil {
+			logrus.WithError(err).Error("failed to claim scheduled jobs")
+			return
+		}
+
+		s.runBatch(ctx, newJobBatch(jobs, claimedAt.Add(s.cfg.JobLease)))
+
+		if len(jobs) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// jobBatch holds the claimed jobs with the ends of their leases. The jobs are run one by one, so the leases
+// of the jobs waiting for their turn are extended together with the lease of the running one.
+type jobBatch struct {
+	mu      sync.Mutex
+	jobs    []storage.ScheduledJob
+	leases  []time.Time
+	running int
+}
+
+func newJobBatch(jobs []storage.ScheduledJob, leaseEnd time.Time) *jobBatch {
+	leases := make([]time.Time, len(jobs))
+
+	for i := range leases {
+		leases[i] = leaseEnd
+	}
+
+	return &jobBatch{jobs: jobs, leases: leases}
+}
+
+// start marks the job as running and reports whether its lease is still held. The job with the expired lease
+// may be claimed by another replica, so it is left to the replica or to the next poll.
+func (b *jobBatch) start(i int) (storage.ScheduledJob, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.running = i
+
+	return b.jobs[i], time.Now().Before(b.leases[i])
+}
+
+// pending returns the running job and the jobs after it.
+func (b *jobBatch) pending() (int, []storage.ScheduledJob) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.running, slices.Clone(b.jobs[b.running:])
+}
+
+func (b *jobBatch) extended(i int, leaseEnd time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.leases[i] = leaseEnd
+}
+
+func (s *SchedulerService) runBatch(ctx context.Context, batch *jobBatch) {
+	if len(batch.jobs) == 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go s.keepJobLeases(ctx, batch, done)
+
+	for i := range batch.jobs {
+		job, leased := batch.start(i)
+
+		if !leased {
+			logrus.WithField("jobID", job.ID).Warn("scheduled job lease expired before run, skip it")
+			continue
+		}
+
+		s.runJob(ctx, &job)
+	}
+}
+
+func (s *SchedulerService) runJob(ctx context.Context, job *storage.ScheduledJob) {
+	log := logrus.WithFields(logrus.Fields{
+		"jobID":   job.ID,
+		"jobType": job.Type,
+		"chatID":  job.ChatID,
+	})
+
+	err := s.execute(ctx, job)
+
+	if err == nil {
+		log.Debug("scheduled job completed")
+
+		if err = s.storage.CompleteJob(ctx, job); err != nil {
+			log.WithError(err).Error("failed to complete scheduled job")
+		}
+
+		return
+	}
+
+	if ctx.Err() != nil {
+		// the lease expires and the job is run again after restart
+		return
+	}
+
+	job.Attempts++
+	job.LastError = err.Error()
+
+	if errors.Is(err, errUnknownJobType) || job.Attempts >= s.cfg.MaxAttempts {
+		log.WithError(err).Error("scheduled job failed, drop it")
+
+		if err = s.storage.CompleteJob(ctx, job); err != nil {
+			log.WithError(err).Error("failed to drop scheduled job")
+		}
+
+		return
+	}
+
+	job.RunAt = time.Now().Add(s.retryDelay(err))
+
+	log.WithError(err).WithField("runAt", job.RunAt).Warn("scheduled job failed, retry later")
+
+	if err = s.storage.RetryJob(ctx, job); err != nil {
+		log.WithError(err).Error("failed to reschedule job")
+	}
+}
+
+// keepJobLeases extends the leases of the running job and of the jobs waiting after it until the batch is done,
+// so a job slowed down by retries and limits does not let another replica claim the rest of the batch.
+func (s *SchedulerService) keepJobLeases(ctx context.Context, batch *jobBatch, done <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.JobLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		running, jobs := batch.pending()
+
+		for i := range jobs {
+			extendedAt := time.Now()
+
+			if err := s.storage.ExtendJobLease(ctx, &jobs[i], s.cfg.JobLease); err != nil {
+				logrus.WithError(err).WithField("jobID", jobs[i].ID).Error("failed to extend scheduled job lease")
+				continue
+			}
+
+			batch.extended(running+i, extendedAt.Add(s.cfg.JobLease))
+		}
+	}
+}
+
+func (s *SchedulerService) execute(ctx context.Context, job *storage.ScheduledJob) error {
+	switch job.Type {
+	case storage.ScheduledJobSend:
+		options := []client.MessageOptions{
+			client.WithSendParseMode(job.ParseMode),
+			client.WithSendEntities(job.Entities),
+			client.WithSendThread(job.ThreadID),
+		}
+
+		if job.InlineKeyboard != nil {
+			options = append(options, client.WithSendInlineKeyboard(*job.InlineKeyboard))
+		}
+
+		_, err := s.telegramClient.SendMessage(ctx, job.ChatID, job.Text, options...)
+
+		return err
+
+	case storage.ScheduledJobEdit:
+		options := []client.EditMessageOptions{
+			client.WithEditMessageText(job.Text),
+			client.WithEditParseMode(job.ParseMode),
+			client.WithEditEntities(job.Entities),
+		}
+
+		if job.InlineKeyboard != nil {
+			options = append(options, client.WithEditInlineKeyboard(*job.InlineKeyboard))
+		}
+
+		return s.telegramClient.EditMessage(ctx, job.ChatID, job.MessageID, options...)
+
+	case storage.ScheduledJobDelete:
+		return s.telegramClient.DeleteMessage(ctx, job.ChatID, job.MessageID)
+
+	case storage.ScheduledJobHandler:
+		handler, ok := s.handlers[job.HandlerName]
+
+		if !ok {
+			return fmt.Errorf("%w: handler %s is not registered", errUnknownJobType, job.HandlerName)
+		}
+
+		return handler(ctx, job)
+	}
+
+	return errUnknownJobType
+}
+
+func (s *SchedulerService) retryDelay(err error) time.Duration {
+	var floodWait *client.FloodWaitError
+
+	if errors.As(err, &floodWait) && floodWait.RetryAfter > s.cfg.RetryDelay {
+		return floodWait.RetryAfter
+	}
+
+	return s.cfg.RetryDelay
+}
+
+// NextLocalTime returns the nearest moment after now when the clock in the location shows hour:minute,
+// e.g. to post at 09:00 in the user's timezone.
+func NextLocalTime(now time.Time, location *time.Location, hour, minute int) time.Time {
+	local := now.In(location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, location)
+
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, location)
+	}
+
+	return next
+}
+
+func newJob(jobID string, jobType storage.ScheduledJobType, runAt time.Time, chatID int64) *storage.ScheduledJob {
+	if jobID == "" {
+		jobID = randomJobID()
+	}
+
+	return &storage.ScheduledJob{
+		ID:        jobID,
+		Type:      jobType,
+		ChatID:    chatID,
+		RunAt:     runAt,
+		CreatedAt: time.Now(),
+	}
+}
+
+func randomJobID() string {
+	buf := make([]byte, 16)
+	_, _ = cryptorand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
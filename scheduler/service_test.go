@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+)
+
+func TestNextLocalTime(t *testing.T) {
+	location := time.FixedZone("UTC+3", 3*60*60)
+	now := time.Date(2024, 5, 10, 5, 30, 0, 0, time.UTC) // 08:30 local
+
+	next := NextLocalTime(now, location, 9, 0)
+
+	if !next.Equal(time.Date(2024, 5, 10, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("next = %v, want today 09:00 local", next)
+	}
+
+	next = NextLocalTime(now, location, 8, 30)
+
+	if !next.Equal(time.Date(2024, 5, 11, 5, 30, 0, 0, time.UTC)) {
+		t.Errorf("next = %v, want tomorrow 08:30 local", next)
+	}
+}
+
+// newBlockingScheduler runs the "job" handler, which calls during before failing with the result.
+func newBlockingScheduler(during func(ctx context.Context, s *SchedulerService), result error) *SchedulerService {
+	s := NewSchedulerService(config.SchedulerConfig{RetryDelay: time.Millisecond}, storage.NewInMemorySchedulerStorage(), nil)
+
+	s.RegisterJobHandler("job", func(ctx context.Context, _ *storage.ScheduledJob) error {
+		during(ctx, s)
+		return result
+	})
+
+	return s
+}
+
+func TestReplaceWhileRunning(t *testing.T) {
+	for _, result := range []error{nil, errors.New("failed")} {
+		s := newBlockingScheduler(func(ctx context.Context, s *SchedulerService) {
+			_, _ = s.ScheduleHandler(ctx, "id", time.Now().Add(time.Hour), 1, "job", "replaced")
+		}, result)
+
+		_, _ = s.ScheduleHandler(t.Context(), "id", time.Now(), 1, "job", "original")
+
+		s.runDueJobs(t.Context())
+
+		if job, err := s.GetJob(t.Context(), "id"); err != nil || job.Payload != "replaced" || job.Attempts != 0 {
+			t.Fatalf("expected job replaced while running to stay, got %+v, %v", job, err)
+		}
+	}
+}
+
+func TestCancelWhileRunning(t *testing.T) {
+	s := newBlockingScheduler(func(ctx context.Context, s *SchedulerService) {
+		if err := s.Cancel(ctx, "id"); err != nil {
+			t.Errorf("cancel running job: %v", err)
+		}
+	}, errors.New("failed"))
+
+	_, _ = s.ScheduleHandler(t.Context(), "id", time.Now(), 1, "job", "")
+
+	s.runDueJobs(t.Context())
+
+	if _, err := s.GetJob(t.Context(), "id"); !errors.Is(err, domain.ErrorJobNotFound) {
+		t.Fatalf("expected job canceled while running not to be retried, got %v", err)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	cfg := withDefaults(config.SchedulerConfig{})
+
+	if cfg.PollInterval <= 0 || cfg.BatchSize <= 0 || cfg.JobLease <= 0 || cfg.MaxAttempts <= 0 || cfg.RetryDelay <= 0 {
+		t.Fatalf("expected defaults for zero config, got %+v", cfg)
+	}
+}
+
+func TestLeaseExpiresMidBatch(t *testing.T) {
+	const lease = 30 * time.Millisecond
+
+	shared := storage.NewInMemorySchedulerStorage()
+	cfg := config.SchedulerConfig{BatchSize: 10, JobLease: lease}
+	first := NewSchedulerService(cfg, shared, nil)
+	second := NewSchedulerService(cfg, shared, nil)
+
+	var mu sync.Mutex
+	runs := make(map[string]int)
+
+	handler := func(ctx context.Context, job *storage.ScheduledJob) error {
+		mu.Lock()
+		runs[job.ID]++
+		mu.Unlock()
+
+		if job.ID == "slow" {
+			// the lease claimed with the batch expires, the other replica polls meanwhile
+			time.Sleep(3 * lease)
+			second.runDueJobs(ctx)
+		}
+
+		return nil
+	}
+
+	first.RegisterJobHandler("job", handler)
+	second.RegisterJobHandler("job", handler)
+
+	_, _ = first.ScheduleHandler(t.Context(), "slow", time.Now().Add(-time.Second), 1, "job", "")
+	_, _ = first.ScheduleHandler(t.Context(), "next", time.Now(), 1, "job", "")
+
+	first.runDueJobs(t.Context())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if runs["slow"] != 1 || runs["next"] != 1 {
+		t.Fatalf("expected every job of the batch to run once, got %v", runs)
+	}
+}
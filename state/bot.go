@@ -0,0 +1,23 @@
+package state
+
+import (
+	"context"
+
+	"github.com/nejkit/telegram-bot-core/v2/client"
+)
+
+type botCtxKey struct{}
+
+// BotInstance describes the bot which received the update. Handlers shared by several bots reply
+// with its client.
+type BotInstance struct {
+	Name   string
+	Client *client.TelegramClient
+}
+
+// BotFromContext returns the bot which received the handled update, it is nil outside of handlers.
+func BotFromContext(ctx context.Context) *BotInstance {
+	bot, _ := ctx.Value(botCtxKey{}).(*BotInstance)
+
+	return bot
+}
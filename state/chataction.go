@@ -0,0 +1,80 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+// chatActionInterval repeats the chat action before telegram hides it after 5 seconds.
+const chatActionInterval = 4 * time.Second
+
+// EnableChatActions makes the service show the chat action to the user while the command, action or callback
+// handler runs longer than the delay. Handlers show typing unless another action is set for them.
+func (t *TelegramStateService[Action, Command, Callback]) EnableChatActions(delay time.Duration) *TelegramStateService[Action, Command, Callback] {
+	t.chatActionDelay = delay
+	t.chatActionRepeat = chatActionInterval
+
+	return t
+}
+
+// SetCommandChatAction sets the chat action of the command handler, e.g. upload_document. It may be set before
+// or after the handler is registered.
+func (t *TelegramStateService[Action, Command, Callback]) SetCommandChatAction(cmd Command, chatAction models.ChatAction) *TelegramStateService[Action, Command, Callback] {
+	t.commandChatActions[cmd] = chatAction
+
+	return t
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) SetActionChatAction(action Action, chatAction models.ChatAction) *TelegramStateService[Action, Command, Callback] {
+	t.actionChatActions[action] = chatAction
+
+	return t
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) SetCallbackChatAction(callback Callback, chatAction models.ChatAction) *TelegramStateService[Action, Command, Callback] {
+	t.callbackChatActions[callback] = chatAction
+
+	return t
+}
+
+// callHandler runs the handler, recovering its panic, and shows the chat action when chat actions are enabled.
+// Empty chat action shows typing.
+func (t *TelegramStateService[Action, Command, Callback]) callHandler(ctx context.Context, chatID int64, info HandlerInfo, chatAction models.ChatAction, update *models.Update) error {
+	if t.chatActionDelay <= 0 || chatID == 0 {
+		return safeCall(ctx, info.Handler, update)
+	}
+
+	if chatAction == "" {
+		chatAction = models.ChatActionTyping
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go t.showChatAction(ctx, chatID, UpdateThreadID(update), chatAction, done)
+
+	return safeCall(ctx, info.Handler, update)
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) showChatAction(ctx context.Context, chatID int64, threadID int, chatAction models.ChatAction, done <-chan struct{}) {
+	timer := time.NewTimer(t.chatActionDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-timer.C:
+			if err := t.telegramClient.SendChatAction(ctx, chatID, threadID, chatAction); err != nil {
+				logrus.WithError(err).WithField("chatID", chatID).Warn("failed send chat action")
+			}
+
+			timer.Reset(t.chatActionRepeat)
+		}
+	}
+}
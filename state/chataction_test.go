@@ -0,0 +1,45 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+)
+
+func TestChatActionDelayAndRepeat(t *testing.T) {
+	telegramClient, calls := newTestClient(t, nil)
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, nil, nil, telegramClient, nil).
+		EnableChatActions(30*time.Millisecond).
+		SetCommandChatAction("report", models.ChatActionUploadDocument).
+		RegisterCommandHandler("report", func(context.Context, *models.Update) error {
+			time.Sleep(80 * time.Millisecond)
+			return nil
+		})
+
+	service.chatActionRepeat = 20 * time.Millisecond
+
+	update := &models.Update{Message: &models.Message{Chat: models.Chat{ID: 1}}}
+	fast := HandlerInfo{Handler: func(context.Context, *models.Update) error { return nil }}
+
+	if err := service.callHandler(t.Context(), 1, fast, "", update); err != nil || len(calls()) != 0 {
+		t.Fatalf("expected no chat action for handler faster than the delay, got %v", calls())
+	}
+
+	err := service.callHandler(t.Context(), 1, service.commandHandler["report"], service.commandChatActions["report"], update)
+
+	actions := calls()
+
+	if err != nil || len(actions) < 2 {
+		t.Fatalf("expected chat action repeated while the handler runs, got %v", actions)
+	}
+
+	for _, call := range actions {
+		if call.method != "sendChatAction" || call.params["action"] != string(models.ChatActionUploadDocument) {
+			t.Fatalf("expected chat action set before registration, got %+v", call)
+		}
+	}
+}
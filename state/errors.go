@@ -0,0 +1,183 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/sirupsen/logrus"
+)
+
+type HandlerKind string
+
+const (
+	HandlerKindCommand         HandlerKind = "command"
+	HandlerKindAction          HandlerKind = "action"
+	HandlerKindCallback        HandlerKind = "callback"
+	HandlerKindRoute           HandlerKind = "route"
+	HandlerKindDefault         HandlerKind = "default"
+	HandlerKindMiddleware      HandlerKind = "middleware"
+	HandlerKindLimiter         HandlerKind = "limiter"
+	HandlerKindMigration       HandlerKind = "migration"
+	HandlerKindMyChatMember    HandlerKind = "my_chat_member"
+	HandlerKindChatMember      HandlerKind = "chat_member"
+	HandlerKindChatJoinRequest HandlerKind = "chat_join_request"
+	HandlerKindInline          HandlerKind = "inline"
+	HandlerKindPayment         HandlerKind = "payment"
+	// HandlerKindUpdate is reported for panics outside of handlers, e.g. in validators or storages.
+	HandlerKindUpdate HandlerKind = "update"
+)
+
+// ErrorHandlerFunc receives errors returned by handlers and recovered panics.
+type ErrorHandlerFunc func(ctx context.Context, update *models.Update, kind HandlerKind, err error)
+
+// PanicError is reported to the error handler when the handler panics.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic: %v", e.Value)
+}
+
+// UserError carries the localization key of the message sent to the user when the handler returns it.
+type UserError struct {
+	LocaleKey string
+	Args      []any
+	err       error
+}
+
+// NewUserError creates the error shown to the user as the localized message of the key.
+func NewUserError(localeKey string, args ...any) error {
+	return &UserError{LocaleKey: localeKey, Args: args}
+}
+
+// WrapUserError shows the localized message of the key to the user and keeps err for the error handler.
+func WrapUserError(err error, localeKey string, args ...any) error {
+	return &UserError{LocaleKey: localeKey, Args: args, err: err}
+}
+
+func (e *UserError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+
+	return e.LocaleKey
+}
+
+func (e *UserError) Unwrap() error {
+	return e.err
+}
+
+// handlerError carries the kind of the handler which returned the error through the global middlewares.
+type handlerError struct {
+	kind HandlerKind
+	err  error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *handlerError) Unwrap() error {
+	return e.err
+}
+
+func withHandlerKind(kind HandlerKind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &handlerError{kind: kind, err: err}
+}
+
+// handlerErrorKind returns the kind of the handler which returned the error, errors returned by the middlewares
+// themselves are of HandlerKindMiddleware. The error not wrapped by the middlewares is returned as it was returned
+// by the handler.
+func handlerErrorKind(err error) (HandlerKind, error) {
+	var handlerErr *handlerError
+
+	if !errors.As(err, &handlerErr) {
+		return HandlerKindMiddleware, err
+	}
+
+	if err == error(handlerErr) {
+		return handlerErr.kind, handlerErr.err
+	}
+
+	return handlerErr.kind, err
+}
+
+// RegisterErrorHandler sets the handler of errors returned by handlers and recovered panics. Errors are
+// logged and user errors are sent to the chat regardless of the handler.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterErrorHandler(handler ErrorHandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.errorHandler = handler
+
+	return t
+}
+
+// safeCall calls the handler and returns its panic as PanicError.
+func safeCall(ctx context.Context, handler HandlerFunc, update *models.Update) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return handler(ctx, update)
+}
+
+// recoverUpdate reports the panic raised while processing the update, it has to be deferred.
+func (t *TelegramStateService[Action, Command, Callback]) recoverUpdate(ctx context.Context, update *models.Update) {
+	if r := recover(); r != nil {
+		t.reportError(ctx, update, HandlerKindUpdate, &PanicError{Value: r, Stack: debug.Stack()})
+	}
+}
+
+// reportError logs the handler error, sends the message of the user error to the chat and calls the error handler.
+func (t *TelegramStateService[Action, Command, Callback]) reportError(ctx context.Context, update *models.Update, kind HandlerKind, err error) {
+	log := logrus.WithFields(logrus.Fields{
+		"updateID": update.ID,
+		"kind":     kind,
+	})
+
+	var panicErr *PanicError
+
+	if errors.As(err, &panicErr) {
+		log.WithError(err).WithField("stack", string(panicErr.Stack)).Error("recovered handler panic")
+	} else {
+		log.WithError(err).Error("failed handle event")
+	}
+
+	var userErr *UserError
+
+	if errors.As(err, &userErr) && t.locales != nil {
+		if chat := UpdateChat(update); chat != nil {
+			text := t.locales.GetWithCulture(getLangFromContext(ctx), userErr.LocaleKey, userErr.Args...)
+
+			if _, sendErr := t.telegramClient.SendMessage(ctx, chat.ID, text, client.WithSendThread(UpdateThreadID(update))); sendErr != nil {
+				log.WithError(sendErr).Error("failed send error message to telegram")
+			}
+		}
+	}
+
+	t.notifyErrorHandler(ctx, update, kind, err)
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) notifyErrorHandler(ctx context.Context, update *models.Update, kind HandlerKind, err error) {
+	if t.errorHandler == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithField("updateID", update.ID).WithField("panic", r).Error("recovered error handler panic")
+		}
+	}()
+
+	t.errorHandler(ctx, update, kind, err)
+}
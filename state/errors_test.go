@@ -0,0 +1,81 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/locale"
+)
+
+func TestProcessUpdateRecoversPanic(t *testing.T) {
+	var reportedKind HandlerKind
+	var reportedErr error
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, nil, nil, nil, nil).
+		RegisterChatMemberHandler(func(context.Context, *models.Update) error {
+			panic("boom")
+		}).
+		RegisterErrorHandler(func(_ context.Context, _ *models.Update, kind HandlerKind, err error) {
+			reportedKind = kind
+			reportedErr = err
+		})
+
+	omitChats := make(chan int64, 1)
+
+	service.processUpdate(context.Background(), queuedUpdate{queueKey: 42, update: &models.Update{ChatMember: &models.ChatMemberUpdated{}}}, omitChats)
+
+	if chatID := <-omitChats; chatID != 42 {
+		t.Fatalf("expected chat 42 to be released, got %d", chatID)
+	}
+
+	var panicErr *PanicError
+
+	if reportedKind != HandlerKindChatMember || !errors.As(reportedErr, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("expected chat member panic to be reported, got %s: %v", reportedKind, reportedErr)
+	}
+}
+
+func TestUserErrorUnwrap(t *testing.T) {
+	cause := errors.New("cause")
+
+	err := WrapUserError(cause, "error_key")
+
+	var userErr *UserError
+
+	if !errors.As(err, &userErr) || userErr.LocaleKey != "error_key" || !errors.Is(err, cause) {
+		t.Fatalf("expected user error wrapping cause, got %v", err)
+	}
+}
+
+func TestUserErrorReplyInTopic(t *testing.T) {
+	localesFile := filepath.Join(t.TempDir(), "locales.json")
+
+	if err := os.WriteFile(localesFile, []byte(`{"defaultCulture":"en","localizedContent":{"error_key":{"en":"Try again"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	telegramClient, calls := newTestClient(t, func(method string) any {
+		if method == "sendMessage" {
+			return models.Message{ID: 1}
+		}
+
+		return nil
+	})
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, nil, nil, telegramClient, locale.NewLocalizationProvider(localesFile))
+
+	update := &models.Update{Message: &models.Message{Chat: models.Chat{ID: -100}, IsTopicMessage: true, MessageThreadID: 5}}
+
+	service.reportError(context.Background(), update, HandlerKindCommand, NewUserError("error_key"))
+
+	sent := calls()
+
+	if len(sent) != 1 || sent[0].params["text"] != "Try again" || sent[0].params["message_thread_id"] != "5" {
+		t.Fatalf("expected error message sent to the topic, got %+v", sent)
+	}
+}
@@ -0,0 +1,131 @@
+package state
+
+import (
+	"context"
+	"regexp"
+	"slices"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+)
+
+// Middleware wraps the handler, it may run code before and after the next handler or stop the update
+// by not calling it.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain wraps the handler with the middlewares, the first middleware is the outermost one.
+func Chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// AdaptMiddleware converts the MiddlewareFunc, the next handler is called only when it succeeds.
+func AdaptMiddleware(middleware MiddlewareFunc) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, update *models.Update) error {
+			ctx, ok := middleware(ctx, update)
+
+			if !ok {
+				return nil
+			}
+
+			return next(ctx, update)
+		}
+	}
+}
+
+// Use adds global middlewares, they wrap routing of messages, callbacks and inline updates, so they
+// run before validators and receive errors returned by handlers. Payment and chat member updates bypass them.
+func (t *TelegramStateService[Action, Command, Callback]) Use(middlewares ...Middleware) *TelegramStateService[Action, Command, Callback] {
+	t.middlewares = append(t.middlewares, middlewares...)
+
+	return t
+}
+
+// HandlerGroup registers handlers of the service wrapped with the middlewares of the group,
+// e.g. an admin check for all admin commands. The middlewares run before validators of the handlers.
+type HandlerGroup[Action storage.UserAction, Command BotCommand, Callback CallbackPrefix] struct {
+	service     *TelegramStateService[Action, Command, Callback]
+	middlewares []Middleware
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) Group(middlewares ...Middleware) *HandlerGroup[Action, Command, Callback] {
+	return &HandlerGroup[Action, Command, Callback]{
+		service:     t,
+		middlewares: middlewares,
+	}
+}
+
+// Group creates the nested group, its handlers are wrapped with the middlewares of both groups.
+func (g *HandlerGroup[Action, Command, Callback]) Group(middlewares ...Middleware) *HandlerGroup[Action, Command, Callback] {
+	return &HandlerGroup[Action, Command, Callback]{
+		service:     g.service,
+		middlewares: append(slices.Clone(g.middlewares), middlewares...),
+	}
+}
+
+// With registers the handlers with the middlewares, e.g. an admin check of one command:
+// service.With(adminOnly).RegisterCommandHandler("ban", ban). Like middlewares of groups, they run before validators.
+func (t *TelegramStateService[Action, Command, Callback]) With(middlewares ...Middleware) *HandlerGroup[Action, Command, Callback] {
+	return t.Group(middlewares...)
+}
+
+// handlerInfo keeps the middlewares of the group apart from the handler, so they wrap its validators as well.
+func (g *HandlerGroup[Action, Command, Callback]) handlerInfo(handler HandlerFunc, validators []ValidatorFunc) HandlerInfo {
+	return HandlerInfo{
+		Handler:           handler,
+		MessageValidators: validators,
+		Middlewares:       g.middlewares,
+	}
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterCommandHandler(cmd Command, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.commandHandler[cmd] = g.handlerInfo(handler, validators)
+
+	return g
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterActionHandler(action Action, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.actionHandler[action] = g.handlerInfo(handler, validators)
+
+	return g
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterCallbackHandler(callback Callback, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.callbackHandler[callback] = g.handlerInfo(handler, validators)
+
+	return g
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterTextHandler(text string, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.textHandler[text] = g.handlerInfo(handler, validators)
+
+	return g
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterLocalizedTextHandler(localeKey string, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.addLocalizedTextRoute(localizedTextRoute{localeKey: localeKey, info: g.handlerInfo(handler, validators)})
+
+	return g
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterRegexpHandler(pattern *regexp.Regexp, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.regexpHandlers = append(g.service.regexpHandlers, regexpRoute{pattern: pattern, info: g.handlerInfo(handler, validators)})
+
+	return g
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterContentTypeHandler(contentType ContentType, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.contentTypeHandler[contentType] = g.handlerInfo(handler, validators)
+
+	return g
+}
+
+func (g *HandlerGroup[Action, Command, Callback]) RegisterChatTypeHandler(chatType models.ChatType, handler HandlerFunc, validators ...ValidatorFunc) *HandlerGroup[Action, Command, Callback] {
+	g.service.chatTypeHandler[chatType] = g.handlerInfo(handler, validators)
+
+	return g
+}
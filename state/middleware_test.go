@@ -0,0 +1,139 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+
+	middleware := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, update *models.Update) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, update)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+
+	handler := Chain(func(context.Context, *models.Update) error {
+		calls = append(calls, "handler")
+		return nil
+	}, middleware("outer"), middleware("inner"))
+
+	_ = handler(context.Background(), &models.Update{})
+
+	expected := []string{"outer before", "inner before", "handler", "inner after", "outer after"}
+
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("expected %v, got %v", expected, calls)
+	}
+}
+
+func TestAdaptMiddleware(t *testing.T) {
+	called := false
+
+	handler := Chain(func(context.Context, *models.Update) error {
+		called = true
+		return nil
+	}, AdaptMiddleware(func(ctx context.Context, _ *models.Update) (context.Context, bool) {
+		return ctx, false
+	}))
+
+	_ = handler(context.Background(), &models.Update{})
+
+	if called {
+		t.Fatal("expected handler to be skipped by failed middleware")
+	}
+}
+
+func commandUpdate(command string) *models.Update {
+	return &models.Update{Message: &models.Message{
+		Text:     "/" + command,
+		Chat:     models.Chat{ID: 1},
+		From:     &models.User{ID: 1},
+		Entities: []models.MessageEntity{{Type: models.MessageEntityTypeBotCommand, Length: len(command) + 1}},
+	}}
+}
+
+func TestGlobalMiddlewareReceivesHandlerError(t *testing.T) {
+	handlerErr := errors.New("failed")
+
+	var (
+		middlewareErr error
+		reported      []HandlerKind
+	)
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, newTestActionStorage(), nil, nil, nil).
+		Use(func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, update *models.Update) error {
+				middlewareErr = next(ctx, update)
+				return middlewareErr
+			}
+		}).
+		RegisterErrorHandler(func(_ context.Context, _ *models.Update, kind HandlerKind, err error) {
+			if err != handlerErr {
+				t.Errorf("expected the handler error reported as returned, got %v", err)
+			}
+
+			reported = append(reported, kind)
+		}).
+		RegisterCommandHandler("start", func(context.Context, *models.Update) error {
+			return handlerErr
+		})
+
+	service.handleUpdate(context.Background(), commandUpdate("start"))
+
+	if !errors.Is(middlewareErr, handlerErr) {
+		t.Fatalf("expected middleware to receive the handler error, got %v", middlewareErr)
+	}
+
+	if !slices.Equal(reported, []HandlerKind{HandlerKindCommand}) {
+		t.Fatalf("expected the error reported once as command error, got %v", reported)
+	}
+}
+
+func TestHandlerMiddlewaresRunBeforeValidators(t *testing.T) {
+	deny := func(HandlerFunc) HandlerFunc {
+		return func(context.Context, *models.Update) error {
+			return nil
+		}
+	}
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, newTestActionStorage(), nil, nil, nil)
+
+	registrations := map[string]func(handler HandlerFunc, validator ValidatorFunc){
+		"group": func(handler HandlerFunc, validator ValidatorFunc) {
+			service.Group(deny).RegisterCommandHandler("group", handler, validator)
+		},
+		"with": func(handler HandlerFunc, validator ValidatorFunc) {
+			service.With(deny).RegisterCommandHandler("with", handler, validator)
+		},
+	}
+
+	for command, register := range registrations {
+		validated, handled := false, false
+
+		register(func(context.Context, *models.Update) error {
+			handled = true
+			return nil
+		}, func(*models.Update) error {
+			validated = true
+			return nil
+		})
+
+		service.handleUpdate(context.Background(), commandUpdate(command))
+
+		if validated || handled {
+			t.Fatalf("%s: expected middleware to stop the update before validators, validated %v, handled %v", command, validated, handled)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPaymentErrorMessage  = "Payment can not be processed now, please try again later"
+	defaultShippingErrorMessage = "Shipping is not available"
+	defaultPaymentAnswerTimeout = 8 * time.Second
+)
+
+// ShippingQueryHandlerFunc returns shipping options available for the address of the query.
+type ShippingQueryHandlerFunc func(ctx context.Context, update *models.Update) ([]models.ShippingOption, error)
+
+// PreCheckoutQueryHandlerFunc confirms the payment by returning nil.
+type PreCheckoutQueryHandlerFunc func(ctx context.Context, update *models.Update) error
+
+type paymentRejectError struct {
+	message string
+}
+
+func (e *paymentRejectError) Error() string {
+	return e.message
+}
+
+// RejectPayment makes the shipping or pre-checkout handler reject the query with the message shown to the user.
+// Other handler errors reject the query with a generic message.
+func RejectPayment(message string) error {
+	return &paymentRejectError{message: message}
+}
+
+// RegisterShippingQueryHandler sets the handler of shipping queries of flexible invoices. The query is
+// rejected when the handler does not return within the payment answer timeout.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterShippingQueryHandler(handler ShippingQueryHandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.shippingQueryHandler = handler
+
+	return t
+}
+
+// RegisterPreCheckoutQueryHandler sets the handler confirming payments. Without the handler every payment
+// is rejected, the query is also rejected when the handler does not return within the payment answer timeout.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterPreCheckoutQueryHandler(handler PreCheckoutQueryHandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.preCheckoutQueryHandler = handler
+
+	return t
+}
+
+// RegisterSuccessfulPaymentHandler sets the handler of successful_payment messages, they are handled before
+// the middleware.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterSuccessfulPaymentHandler(handler HandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.successfulPaymentHandler = handler
+
+	return t
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) RegisterRefundedPaymentHandler(handler HandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.refundedPaymentHandler = handler
+
+	return t
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) handleShippingQuery(ctx context.Context, update *models.Update) {
+	log := logrus.WithFields(logrus.Fields{
+		"updateID": update.ID,
+		"userID":   update.ShippingQuery.From.ID,
+	})
+
+	var shippingOptions []models.ShippingOption
+
+	errorMessage := defaultShippingErrorMessage
+
+	if t.shippingQueryHandler != nil {
+		var err error
+
+		shippingOptions, err = callPaymentHandler(ctx, t.paymentAnswerTimeout, func(handlerCtx context.Context) ([]models.ShippingOption, error) {
+			return t.shippingQueryHandler(handlerCtx, update)
+		})
+
+		errorMessage = paymentErrorMessage(err, log)
+
+		if paymentHandlerFailed(err) {
+			t.notifyErrorHandler(ctx, update, HandlerKindPayment, err)
+		}
+	}
+
+	if err := t.telegramClient.AnswerShippingQuery(ctx, update.ShippingQuery.ID, shippingOptions, errorMessage); err != nil {
+		log.WithError(err).Error("failed answer shipping query")
+	}
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) handlePreCheckoutQuery(ctx context.Context, update *models.Update) {
+	log := logrus.WithFields(logrus.Fields{
+		"updateID": update.ID,
+		"userID":   update.PreCheckoutQuery.From.ID,
+	})
+
+	errorMessage := defaultPaymentErrorMessage
+
+	if t.preCheckoutQueryHandler == nil {
+		log.Warn("pre-checkout query handler is not registered, payment is rejected")
+	} else {
+		_, err := callPaymentHandler(ctx, t.paymentAnswerTimeout, func(handlerCtx context.Context) (struct{}, error) {
+			return struct{}{}, t.preCheckoutQueryHandler(handlerCtx, update)
+		})
+
+		errorMessage = paymentErrorMessage(err, log)
+
+		if paymentHandlerFailed(err) {
+			t.notifyErrorHandler(ctx, update, HandlerKindPayment, err)
+		}
+	}
+
+	if err := t.telegramClient.AnswerPreCheckoutQuery(ctx, update.PreCheckoutQuery.ID, errorMessage); err != nil {
+		log.WithError(err).Error("failed answer pre-checkout query")
+	}
+}
+
+// callPaymentHandler stops waiting for the handler after the payment answer timeout, so the query is
+// answered before telegram cancels it even when the handler ignores the context.
+func callPaymentHandler[T any](ctx context.Context, timeout time.Duration, handler func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		timeout = defaultPaymentAnswerTimeout
+	}
+
+	handlerCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type handlerResult struct {
+		value T
+		err   error
+	}
+
+	result := make(chan handlerResult, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- handlerResult{err: &PanicError{Value: r, Stack: debug.Stack()}}
+			}
+		}()
+
+		value, err := handler(handlerCtx)
+		result <- handlerResult{value: value, err: err}
+	}()
+
+	select {
+	case res := <-result:
+		return res.value, res.err
+	case <-handlerCtx.Done():
+		var empty T
+		return empty, handlerCtx.Err()
+	}
+}
+
+func paymentErrorMessage(err error, log *logrus.Entry) string {
+	if err == nil {
+		return ""
+	}
+
+	var rejectErr *paymentRejectError
+
+	if errors.As(err, &rejectErr) {
+		return rejectErr.message
+	}
+
+	log.WithError(err).Error("failed handle payment query")
+
+	return defaultPaymentErrorMessage
+}
+
+// paymentHandlerFailed reports whether the handler failed rather than rejected the payment.
+func paymentHandlerFailed(err error) bool {
+	var rejectErr *paymentRejectError
+
+	return err != nil && !errors.As(err, &rejectErr)
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) handlePaymentMessage(ctx context.Context, update *models.Update) {
+	handler := t.successfulPaymentHandler
+
+	if update.Message.RefundedPayment != nil {
+		handler = t.refundedPaymentHandler
+	}
+
+	if handler == nil {
+		return
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"updateID": update.ID,
+		"chatID":   update.Message.Chat.ID,
+	})
+
+	log.Debug("handle payment message")
+
+	if err := safeCall(ctx, handler, update); err != nil {
+		t.reportError(ctx, update, HandlerKindPayment, err)
+	}
+}
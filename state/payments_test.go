@@ -0,0 +1,138 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/client"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/sirupsen/logrus"
+)
+
+// apiCall is the request received by the fake bot api.
+type apiCall struct {
+	method string
+	params map[string]string
+}
+
+// newTestClient creates the client of a fake bot api recording every call, handler returns the result of the method.
+func newTestClient(t *testing.T, handler func(method string) any) (*client.TelegramClient, func() []apiCall) {
+	var (
+		mu    sync.Mutex
+		calls []apiCall
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := path.Base(r.URL.Path)
+
+		var result any = true
+
+		if method == "getMe" {
+			result = models.User{ID: 1, IsBot: true, Username: "test_bot"}
+		} else {
+			_ = r.ParseMultipartForm(1 << 20)
+
+			params := make(map[string]string)
+
+			for key := range r.Form {
+				params[key] = r.FormValue(key)
+			}
+
+			mu.Lock()
+			calls = append(calls, apiCall{method: method, params: params})
+			mu.Unlock()
+
+			if handler != nil {
+				if value := handler(method); value != nil {
+					result = value
+				}
+			}
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+	}))
+
+	t.Cleanup(server.Close)
+
+	telegramClient, err := client.CreateTelegramClient(&config.TelegramConfig{
+		Token:                    "test",
+		TelegramApiUrl:           server.URL,
+		GlobalMessagesPerSecond:  -1,
+		PrivateMessagesPerMinute: -1,
+		GroupMessagesPerMinute:   -1,
+		ChannelMessagesPerMinute: -1,
+		RetryMaxAttempts:         -1,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return telegramClient, func() []apiCall {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]apiCall(nil), calls...)
+	}
+}
+
+func TestCallPaymentHandlerTimeout(t *testing.T) {
+	_, err := callPaymentHandler(context.Background(), 10*time.Millisecond, func(context.Context) (struct{}, error) {
+		time.Sleep(time.Second)
+		return struct{}{}, nil
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+
+	if message := paymentErrorMessage(err, logrus.NewEntry(logrus.StandardLogger())); message != defaultPaymentErrorMessage {
+		t.Fatalf("expected default error message, got %q", message)
+	}
+}
+
+func TestPaymentErrorMessage(t *testing.T) {
+	log := logrus.NewEntry(logrus.StandardLogger())
+
+	if message := paymentErrorMessage(nil, log); message != "" {
+		t.Fatalf("expected confirmation, got %q", message)
+	}
+
+	if message := paymentErrorMessage(RejectPayment("out of stock"), log); message != "out of stock" {
+		t.Fatalf("expected reject message, got %q", message)
+	}
+}
+
+func TestPreCheckoutRejectedWithoutHandler(t *testing.T) {
+	telegramClient, calls := newTestClient(t, nil)
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, nil, nil, telegramClient, nil)
+
+	service.handlePreCheckoutQuery(context.Background(), &models.Update{
+		PreCheckoutQuery: &models.PreCheckoutQuery{ID: "query", From: &models.User{ID: 1}},
+	})
+
+	answers := calls()
+
+	if len(answers) != 1 || answers[0].method != "answerPreCheckoutQuery" || answers[0].params["ok"] != "false" {
+		t.Fatalf("expected pre-checkout rejected without handler, got %+v", answers)
+	}
+
+	service.RegisterPreCheckoutQueryHandler(func(context.Context, *models.Update) error { return nil })
+
+	service.handlePreCheckoutQuery(context.Background(), &models.Update{
+		PreCheckoutQuery: &models.PreCheckoutQuery{ID: "query", From: &models.User{ID: 1}},
+	})
+
+	if answers = calls(); len(answers) != 2 || answers[1].params["ok"] != "true" {
+		t.Fatalf("expected pre-checkout confirmed by handler, got %+v", answers)
+	}
+}
@@ -0,0 +1,52 @@
+package state
+
+import (
+	"context"
+	"sync"
+)
+
+// WorkerPool runs updates of several state services with a shared number of workers.
+// The pool is run by its owner, services only submit updates to it.
+type WorkerPool struct {
+	size    int
+	tasks   chan func()
+	runOnce sync.Once
+}
+
+func NewWorkerPool(size int) *WorkerPool {
+	return &WorkerPool{
+		size:  max(size, 1),
+		tasks: make(chan func()),
+	}
+}
+
+func (p *WorkerPool) Run(ctx context.Context) {
+	p.runOnce.Do(func() {
+		for range p.size {
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case task := <-p.tasks:
+						task()
+					}
+				}
+			}()
+		}
+	})
+}
+
+// Submit waits for a free worker and returns false when the context is done before.
+func (p *WorkerPool) Submit(ctx context.Context, task func()) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case p.tasks <- task:
+		return true
+	}
+}
@@ -0,0 +1,50 @@
+package state
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolLimitsConcurrency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := NewWorkerPool(2)
+	pool.Run(ctx)
+
+	var running, maxRunning atomic.Int32
+	done := make(chan struct{}, 6)
+
+	for range 6 {
+		pool.Submit(ctx, func() {
+			current := running.Add(1)
+
+			for {
+				observed := maxRunning.Load()
+				if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			done <- struct{}{}
+		})
+	}
+
+	for range 6 {
+		<-done
+	}
+
+	if maxRunning.Load() != 2 {
+		t.Fatalf("expected 2 concurrent tasks, got %d", maxRunning.Load())
+	}
+
+	cancel()
+
+	if pool.Submit(ctx, func() {}) {
+		t.Fatal("expected submit to fail after context is done")
+	}
+}
@@ -122,3 +122,27 @@ func TestDeleteMiddleElement(t *testing.T) {
 		t.Error("third.Next != nil")
 	}
 }
+
+func TestThreadQueueKeys(t *testing.T) {
+	var keys threadQueueKeys
+
+	topic := chatThread{chatID: -100, threadID: 5}
+
+	first := keys.acquire(topic)
+
+	if keys.acquire(topic) != first || keys.acquire(chatThread{chatID: -100}) == first {
+		t.Fatal("expected one queue per chat topic")
+	}
+
+	if keys.release(first) {
+		t.Fatal("expected queue with pending update not to drain")
+	}
+
+	if !keys.release(first) {
+		t.Fatal("expected queue to drain after the last update")
+	}
+
+	if next := keys.acquire(topic); next == first || len(keys.keys) != 2 {
+		t.Fatalf("expected drained queue forgotten and its id not reused, got %d, %v", next, keys.keys)
+	}
+}
@@ -0,0 +1,225 @@
+package state
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/sirupsen/logrus"
+)
+
+type ContentType string
+
+const (
+	ContentTypeText       ContentType = "text"
+	ContentTypePhoto      ContentType = "photo"
+	ContentTypeVideo      ContentType = "video"
+	ContentTypeAnimation  ContentType = "animation"
+	ContentTypeDocument   ContentType = "document"
+	ContentTypeAudio      ContentType = "audio"
+	ContentTypeVoice      ContentType = "voice"
+	ContentTypeVideoNote  ContentType = "video_note"
+	ContentTypeSticker    ContentType = "sticker"
+	ContentTypeContact    ContentType = "contact"
+	ContentTypeLocation   ContentType = "location"
+	ContentTypeWebAppData ContentType = "web_app_data"
+	// ContentTypeService matches service messages: members joined or left, chat info changes, pins, topics and video chats.
+	ContentTypeService ContentType = "service"
+	ContentTypeOther   ContentType = "other"
+)
+
+type regexpRoute struct {
+	pattern *regexp.Regexp
+	info    HandlerInfo
+}
+
+type localizedTextRoute struct {
+	localeKey string
+	info      HandlerInfo
+}
+
+// MessageContentType returns the kind of the message content.
+func MessageContentType(m *models.Message) ContentType {
+	switch {
+	case m.WebAppData != nil:
+		return ContentTypeWebAppData
+	case m.Contact != nil:
+		return ContentTypeContact
+	case m.Location != nil:
+		return ContentTypeLocation
+	case len(m.Photo) > 0:
+		return ContentTypePhoto
+	case m.Video != nil:
+		return ContentTypeVideo
+	case m.Animation != nil:
+		return ContentTypeAnimation
+	case m.Document != nil:
+		return ContentTypeDocument
+	case m.Audio != nil:
+		return ContentTypeAudio
+	case m.Voice != nil:
+		return ContentTypeVoice
+	case m.VideoNote != nil:
+		return ContentTypeVideoNote
+	case m.Sticker != nil:
+		return ContentTypeSticker
+	case m.Text != "":
+		return ContentTypeText
+	case isServiceMessage(m):
+		return ContentTypeService
+	}
+
+	return ContentTypeOther
+}
+
+func isServiceMessage(m *models.Message) bool {
+	return len(m.NewChatMembers) > 0 || m.LeftChatMember != nil || m.NewChatTitle != "" || len(m.NewChatPhoto) > 0 ||
+		m.DeleteChatPhoto || m.GroupChatCreated || m.SupergroupChatCreated || m.ChannelChatCreated ||
+		m.MessageAutoDeleteTimerChanged != nil || m.PinnedMessage != nil ||
+		m.ForumTopicCreated != nil || m.ForumTopicEdited != nil || m.ForumTopicClosed != nil || m.ForumTopicReopened != nil ||
+		m.VideoChatScheduled != nil || m.VideoChatStarted != nil || m.VideoChatEnded != nil || m.VideoChatParticipantsInvited != nil ||
+		m.WriteAccessAllowed != nil || m.UsersShared != nil || m.ChatShared != nil || m.BoostAdded != nil ||
+		m.ChatBackgroundSet != nil || m.ProximityAlertTriggered != nil
+}
+
+// RegisterTextHandler routes messages with exactly the text, e.g. of a reply keyboard button. Text routes
+// are checked before the action of the user, so buttons work inside of flows like commands.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterTextHandler(text string, handler HandlerFunc, validators ...ValidatorFunc) *TelegramStateService[Action, Command, Callback] {
+	t.textHandler[text] = HandlerInfo{
+		Handler:           handler,
+		MessageValidators: validators,
+	}
+
+	return t
+}
+
+// RegisterLocalizedTextHandler routes messages with the text of the localization key in any culture. When the text
+// belongs to several keys, the route registered first wins.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterLocalizedTextHandler(localeKey string, handler HandlerFunc, validators ...ValidatorFunc) *TelegramStateService[Action, Command, Callback] {
+	t.addLocalizedTextRoute(localizedTextRoute{
+		localeKey: localeKey,
+		info: HandlerInfo{
+			Handler:           handler,
+			MessageValidators: validators,
+		},
+	})
+
+	return t
+}
+
+// addLocalizedTextRoute replaces the route of the same key in place, so its position is kept.
+func (t *TelegramStateService[Action, Command, Callback]) addLocalizedTextRoute(route localizedTextRoute) {
+	for i := range t.localizedTextHandler {
+		if t.localizedTextHandler[i].localeKey == route.localeKey {
+			t.localizedTextHandler[i] = route
+			return
+		}
+	}
+
+	t.localizedTextHandler = append(t.localizedTextHandler, route)
+}
+
+// RegisterRegexpHandler routes messages with the text matching the pattern, patterns are checked in the order
+// of registration after the action of the user.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterRegexpHandler(pattern *regexp.Regexp, handler HandlerFunc, validators ...ValidatorFunc) *TelegramStateService[Action, Command, Callback] {
+	t.regexpHandlers = append(t.regexpHandlers, regexpRoute{
+		pattern: pattern,
+		info: HandlerInfo{
+			Handler:           handler,
+			MessageValidators: validators,
+		},
+	})
+
+	return t
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) RegisterContentTypeHandler(contentType ContentType, handler HandlerFunc, validators ...ValidatorFunc) *TelegramStateService[Action, Command, Callback] {
+	t.contentTypeHandler[contentType] = HandlerInfo{
+		Handler:           handler,
+		MessageValidators: validators,
+	}
+
+	return t
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) RegisterChatTypeHandler(chatType models.ChatType, handler HandlerFunc, validators ...ValidatorFunc) *TelegramStateService[Action, Command, Callback] {
+	t.chatTypeHandler[chatType] = HandlerInfo{
+		Handler:           handler,
+		MessageValidators: validators,
+	}
+
+	return t
+}
+
+// RegisterDefaultHandler sets the handler of messages and callbacks not matched by any route.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterDefaultHandler(handler HandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.defaultHandler = handler
+
+	return t
+}
+
+// matchTextRoute finds the exact or localized text route of the message.
+func (t *TelegramStateService[Action, Command, Callback]) matchTextRoute(m *models.Message) (HandlerInfo, bool) {
+	if m.Text == "" {
+		return HandlerInfo{}, false
+	}
+
+	if info, ok := t.textHandler[m.Text]; ok {
+		return info, true
+	}
+
+	if t.locales == nil {
+		return HandlerInfo{}, false
+	}
+
+	for _, route := range t.localizedTextHandler {
+		if t.locales.HasLocalization(route.localeKey, m.Text) {
+			return route.info, true
+		}
+	}
+
+	return HandlerInfo{}, false
+}
+
+// matchMessageRoute finds the route of the message without action by regexp, content type and chat type in this order.
+func (t *TelegramStateService[Action, Command, Callback]) matchMessageRoute(m *models.Message) (HandlerInfo, bool) {
+	if m.Text != "" {
+		for _, route := range t.regexpHandlers {
+			if route.pattern.MatchString(m.Text) {
+				return route.info, true
+			}
+		}
+	}
+
+	if info, ok := t.contentTypeHandler[MessageContentType(m)]; ok {
+		return info, true
+	}
+
+	if info, ok := t.chatTypeHandler[m.Chat.Type]; ok {
+		return info, true
+	}
+
+	return HandlerInfo{}, false
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) handleRoute(ctx context.Context, chatID int64, update *models.Update, info HandlerInfo, log *logrus.Entry) error {
+	return withHandlerKind(HandlerKindRoute, t.runHandler(ctx, info, update, func(ctx context.Context, update *models.Update) error {
+		if err := t.processValidation(ctx, chatID, update, info.MessageValidators, log, 0); err != nil {
+			return nil
+		}
+
+		return t.callHandler(ctx, chatID, info, "", update)
+	}))
+}
+
+// handleDefault calls the default handler for the update not matched by any route.
+func (t *TelegramStateService[Action, Command, Callback]) handleDefault(ctx context.Context, chatID int64, update *models.Update, log *logrus.Entry) error {
+	if t.defaultHandler == nil {
+		log.Warn("handler not found")
+		return nil
+	}
+
+	log.Debug("no route matched, call default handler")
+
+	return withHandlerKind(HandlerKindDefault, t.callHandler(ctx, chatID, HandlerInfo{Handler: t.defaultHandler}, "", update))
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/locale"
+)
+
+func TestMessageContentType(t *testing.T) {
+	cases := []struct {
+		message  *models.Message
+		expected ContentType
+	}{
+		{&models.Message{Text: "hello"}, ContentTypeText},
+		{&models.Message{Photo: []models.PhotoSize{{FileID: "photo"}}, Caption: "caption"}, ContentTypePhoto},
+		{&models.Message{Animation: &models.Animation{}, Document: &models.Document{}}, ContentTypeAnimation},
+		{&models.Message{Contact: &models.Contact{}}, ContentTypeContact},
+		{&models.Message{WebAppData: &models.WebAppData{}}, ContentTypeWebAppData},
+		{&models.Message{NewChatMembers: []models.User{{ID: 1}}}, ContentTypeService},
+		{&models.Message{}, ContentTypeOther},
+	}
+
+	for _, c := range cases {
+		if contentType := MessageContentType(c.message); contentType != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, contentType)
+		}
+	}
+}
+
+func TestMatchMessageRoute(t *testing.T) {
+	var called string
+
+	route := func(name string) HandlerFunc {
+		return func(context.Context, *models.Update) error {
+			called = name
+			return nil
+		}
+	}
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, nil, nil, nil, nil).
+		RegisterTextHandler("Menu", route("text")).
+		RegisterRegexpHandler(regexp.MustCompile(`^\d+$`), route("regexp")).
+		RegisterContentTypeHandler(ContentTypeText, route("content")).
+		RegisterChatTypeHandler(models.ChatTypePrivate, route("chat"))
+
+	if _, ok := service.matchTextRoute(&models.Message{Text: "42"}); ok {
+		t.Fatal("expected no text route")
+	}
+
+	cases := []struct {
+		message  *models.Message
+		expected string
+	}{
+		{&models.Message{Text: "42"}, "regexp"},
+		{&models.Message{Text: "hello"}, "content"},
+		{&models.Message{Contact: &models.Contact{}, Chat: models.Chat{Type: models.ChatTypePrivate}}, "chat"},
+	}
+
+	for _, c := range cases {
+		info, ok := service.matchMessageRoute(c.message)
+
+		if !ok {
+			t.Fatalf("expected route for %q", c.message.Text)
+		}
+
+		_ = info.Handler(context.Background(), nil)
+
+		if called != c.expected {
+			t.Errorf("expected %s route, got %s", c.expected, called)
+		}
+	}
+
+	info, ok := service.matchTextRoute(&models.Message{Text: "Menu"})
+
+	if !ok {
+		t.Fatal("expected text route")
+	}
+
+	_ = info.Handler(context.Background(), nil)
+
+	if called != "text" {
+		t.Errorf("expected text route, got %s", called)
+	}
+}
+
+func TestLocalizedTextRouteOrder(t *testing.T) {
+	localesFile := filepath.Join(t.TempDir(), "locales.json")
+
+	// "OK" is the text of both keys, the route registered first has to win every time
+	content := `{"defaultCulture":"en","localizedContent":{"confirm":{"en":"OK"},"close":{"en":"Close","de":"OK"}}}`
+
+	if err := os.WriteFile(localesFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var called string
+
+	route := func(name string) HandlerFunc {
+		return func(context.Context, *models.Update) error {
+			called = name
+			return nil
+		}
+	}
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, nil, nil, nil, locale.NewLocalizationProvider(localesFile)).
+		RegisterLocalizedTextHandler("close", route("close")).
+		RegisterLocalizedTextHandler("confirm", route("confirm")).
+		RegisterLocalizedTextHandler("close", route("close again"))
+
+	for range 20 {
+		info, ok := service.matchTextRoute(&models.Message{Text: "OK"})
+
+		if !ok {
+			t.Fatal("expected localized text route")
+		}
+
+		_ = info.Handler(context.Background(), nil)
+
+		if called != "close again" {
+			t.Fatalf("expected first registered key with replaced handler, got %s", called)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-telegram/bot/models"
@@ -11,6 +12,7 @@ import (
 	"github.com/nejkit/telegram-bot-core/v2/config"
 	"github.com/nejkit/telegram-bot-core/v2/limiter"
 	"github.com/nejkit/telegram-bot-core/v2/locale"
+	"github.com/nejkit/telegram-bot-core/v2/scheduler"
 	"github.com/nejkit/telegram-bot-core/v2/storage"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
@@ -29,10 +31,13 @@ type ValidatorFunc func(update *models.Update) error
 type HandlerInfo struct {
 	Handler           HandlerFunc
 	MessageValidators []ValidatorFunc
+	// Middlewares of the group the handler was registered with, they wrap the validators as well.
+	Middlewares []Middleware
 }
 
 type TelegramStateService[Action storage.UserAction, Command BotCommand, Callback CallbackPrefix] struct {
 	chatRequestChannels map[int64]chan *models.Update
+	chatRequestMu       sync.RWMutex
 	processingQueueChan chan struct{}
 
 	telegramClient *client.TelegramClient
@@ -41,17 +46,55 @@ type TelegramStateService[Action storage.UserAction, Command BotCommand, Callbac
 	actionHandler   map[Action]HandlerInfo
 	callbackHandler map[Callback]HandlerInfo
 
+	textHandler          map[string]HandlerInfo
+	localizedTextHandler []localizedTextRoute
+	regexpHandlers       []regexpRoute
+	contentTypeHandler   map[ContentType]HandlerInfo
+	chatTypeHandler      map[models.ChatType]HandlerInfo
+	defaultHandler       HandlerFunc
+	errorHandler         ErrorHandlerFunc
+
 	chatMemberHandler      HandlerFunc
 	myChatMemberHandler    HandlerFunc
 	limiterMessageHandler  HandlerFunc
 	chatMigrationHandler   HandlerFunc
 	chatJoinRequestHandler HandlerFunc
 
+	inlineQueryHandler        HandlerFunc
+	chosenInlineResultHandler HandlerFunc
+
+	shippingQueryHandler     ShippingQueryHandlerFunc
+	preCheckoutQueryHandler  PreCheckoutQueryHandlerFunc
+	successfulPaymentHandler HandlerFunc
+	refundedPaymentHandler   HandlerFunc
+	paymentAnswerTimeout     time.Duration
+
+	// chatlessSemaphore limits concurrent handling of updates without chat, they skip the chat queue
+	chatlessSemaphore chan struct{}
+
+	// workerPool replaces the own workers of the service when it is shared with other bots
+	workerPool *WorkerPool
+	botName    string
+
+	// chatActionDelay enables chat actions shown while slow handlers run, the actions are set per handler
+	chatActionDelay     time.Duration
+	chatActionRepeat    time.Duration
+	commandChatActions  map[Command]models.ChatAction
+	actionChatActions   map[Action]models.ChatAction
+	callbackChatActions map[Callback]models.ChatAction
+
+	// threadKeys keys the chat queue and stored state by chat and forum topic, queueKeys maps them to queue ids
+	threadKeys bool
+	queueKeys  threadQueueKeys
+
 	actionStorage      storage.UserActionStorage
+	sessionStorage     storage.SessionStorage
+	actionScheduler    *scheduler.SchedulerService
+	actionTimeouts     map[Action]actionTimeout
 	messageStorage     storage.UserMessageStorage
 	workersCount       int
 	limiter            *limiter.UserLimiter
-	middlewareFunc     MiddlewareFunc
+	middlewares        []Middleware
 	processor          *MessageProcessor
 	locales            *locale.LocalizationProvider
 	notFlowableActions []Action
@@ -65,12 +108,21 @@ func NewTelegramStateService[Action storage.UserAction, Command BotCommand, Call
 	locales *locale.LocalizationProvider,
 ) *TelegramStateService[Action, Command, Callback] {
 	handler := &TelegramStateService[Action, Command, Callback]{
-		chatRequestChannels: make(map[int64]chan *models.Update),
-		processingQueueChan: make(chan struct{}, cfg.WorkersCount),
-		commandHandler:      make(map[Command]HandlerInfo),
-		actionHandler:       make(map[Action]HandlerInfo),
-		callbackHandler:     make(map[Callback]HandlerInfo),
-		telegramClient:      client,
+		chatRequestChannels:  make(map[int64]chan *models.Update),
+		processingQueueChan:  make(chan struct{}, cfg.WorkersCount),
+		chatlessSemaphore:    make(chan struct{}, max(cfg.WorkersCount, 1)),
+		commandHandler:       make(map[Command]HandlerInfo),
+		actionHandler:        make(map[Action]HandlerInfo),
+		actionTimeouts:       make(map[Action]actionTimeout),
+		callbackHandler:      make(map[Callback]HandlerInfo),
+		textHandler:          make(map[string]HandlerInfo),
+		localizedTextHandler: make([]localizedTextRoute, 0),
+		contentTypeHandler:   make(map[ContentType]HandlerInfo),
+		chatTypeHandler:      make(map[models.ChatType]HandlerInfo),
+		commandChatActions:   make(map[Command]models.ChatAction),
+		actionChatActions:    make(map[Action]models.ChatAction),
+		callbackChatActions:  make(map[Callback]models.ChatAction),
+		telegramClient:       client,
 
 		actionStorage:      actionStorage,
 		messageStorage:     messageStorage,
@@ -79,6 +131,8 @@ func NewTelegramStateService[Action storage.UserAction, Command BotCommand, Call
 		processor:          NewMessageProcessor(),
 		locales:            locales,
 		notFlowableActions: make([]Action, 0),
+
+		paymentAnswerTimeout: cfg.PaymentAnswerTimeout,
 	}
 
 	handler.callbackHandler["set-previous-keyboard"] = HandlerInfo{
@@ -134,7 +188,7 @@ func (t *TelegramStateService[Action, Command, Callback]) handleSetPreviousKeybo
 
 			newKeyboard := keyboardInfo.Keyboards[keyboardInfo.CurrentPosition]
 
-			err = t.telegramClient.EditMessage(ctx, userID, messageInfo.MessageID, client.WithEditInlineKeyboard(newKeyboard))
+			err = t.telegramClient.EditMessageKeyboard(ctx, userID, messageInfo.MessageID, &newKeyboard)
 
 			if err != nil {
 				logrus.WithError(err).Error("Error editing keyboard")
@@ -198,7 +252,7 @@ func (t *TelegramStateService[Action, Command, Callback]) handleSetNextKeyboardP
 
 			newKeyboard := keyboardInfo.Keyboards[keyboardInfo.CurrentPosition]
 
-			err = t.telegramClient.EditMessage(ctx, userID, messageInfo.MessageID, client.WithEditInlineKeyboard(newKeyboard))
+			err = t.telegramClient.EditMessageKeyboard(ctx, userID, messageInfo.MessageID, &newKeyboard)
 
 			if err != nil {
 				logrus.WithError(err).Error("Error editing keyboard")
@@ -270,10 +324,9 @@ func (t *TelegramStateService[Action, Command, Callback]) RegisterLimiterHandler
 	return t
 }
 
+// RegisterMiddlewareHandler adds the middleware to the global middlewares, see Use.
 func (t *TelegramStateService[Action, Command, Callback]) RegisterMiddlewareHandler(handler MiddlewareFunc) *TelegramStateService[Action, Command, Callback] {
-	t.middlewareFunc = handler
-
-	return t
+	return t.Use(AdaptMiddleware(handler))
 }
 
 func (t *TelegramStateService[Action, Command, Callback]) RegisterMigrationHandler(handler HandlerFunc) *TelegramStateService[Action, Command, Callback] {
@@ -288,9 +341,70 @@ func (t *TelegramStateService[Action, Command, Callback]) RegisterChatJoinReques
 	return t
 }
 
+// RegisterInlineQueryHandler sets the handler of inline queries. Inline updates have no chat, so they are
+// handled concurrently instead of the per-chat queue.
+func (t *TelegramStateService[Action, Command, Callback]) RegisterInlineQueryHandler(handler HandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.inlineQueryHandler = handler
+
+	return t
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) RegisterChosenInlineResultHandler(handler HandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.chosenInlineResultHandler = handler
+
+	return t
+}
+
+// UseWorkerPool makes the service handle updates with the shared pool instead of own workers.
+// The pool has to be run by its owner.
+func (t *TelegramStateService[Action, Command, Callback]) UseWorkerPool(pool *WorkerPool) *TelegramStateService[Action, Command, Callback] {
+	t.workerPool = pool
+
+	return t
+}
+
+// EnableThreadKeys makes updates of different forum topics of one chat processed independently.
+// User actions and stored messages are kept per topic as well, see storage.WithThread.
+func (t *TelegramStateService[Action, Command, Callback]) EnableThreadKeys() *TelegramStateService[Action, Command, Callback] {
+	t.threadKeys = true
+
+	return t
+}
+
+// UseSessionStorage makes sessions of users available to handlers through storage.SessionFromContext. The action
+// storage is wrapped with storage.SessionActionStorage, so sessions are cleared when the action returns to 0.
+// Handlers have to save actions through the wrapped storage, returned by ActionStorage and
+// storage.ActionStorageFromContext, changes made through the original storage keep the session.
+func (t *TelegramStateService[Action, Command, Callback]) UseSessionStorage(sessions storage.SessionStorage) *TelegramStateService[Action, Command, Callback] {
+	t.sessionStorage = sessions
+
+	if wrapped, ok := t.actionStorage.(*storage.SessionActionStorage); ok {
+		t.actionStorage = storage.NewSessionActionStorage(wrapped.UserActionStorage, sessions)
+	} else if t.actionStorage != nil {
+		t.actionStorage = storage.NewSessionActionStorage(t.actionStorage, sessions)
+	}
+
+	return t
+}
+
+// ActionStorage returns the action storage used by the service, wrapped by UseSessionStorage if it was called.
+func (t *TelegramStateService[Action, Command, Callback]) ActionStorage() storage.UserActionStorage {
+	return t.actionStorage
+}
+
+// SetBotName sets the name of the bot returned by BotFromContext to handlers.
+func (t *TelegramStateService[Action, Command, Callback]) SetBotName(name string) *TelegramStateService[Action, Command, Callback] {
+	t.botName = name
+
+	return t
+}
+
+// Run handles updates until ctx is done or receiving of updates fails, e.g. the webhook is rejected by telegram.
 func (t *TelegramStateService[Action, Command, Callback]) Run(ctx context.Context) {
+	ctx = context.WithValue(ctx, botCtxKey{}, &BotInstance{Name: t.botName, Client: t.telegramClient})
 	updatesChan := t.telegramClient.GetUpdates(ctx)
 	logrus.Info("start telegram updates handler service")
+	t.warnDisabledActionTimeouts()
 	go t.startConsumeQueueChan(ctx)
 	t.telegramClient.RunChatRatesCleanup(ctx)
 	go t.limiter.Run(ctx)
@@ -300,12 +414,21 @@ func (t *TelegramStateService[Action, Command, Callback]) Run(ctx context.Contex
 		case <-ctx.Done():
 			return
 
+		case err := <-t.telegramClient.UpdatesErr():
+			logrus.WithError(err).Error("failed to receive updates, stop telegram updates handler service")
+			return
+
 		case update, ok := <-updatesChan:
 			if !ok {
 				logrus.Warning("updates chan was closed, polling stopped")
 				return
 			}
 
+			if isChatlessUpdate(update) {
+				go t.handleChatlessUpdate(ctx, update)
+				continue
+			}
+
 			chat := UpdateChat(update)
 			user := UpdateUser(update)
 
@@ -334,8 +457,8 @@ func (t *TelegramStateService[Action, Command, Callback]) Run(ctx context.Contex
 			if withRateCheck && !t.limiter.Check(userID) {
 				log.Debug("rate limit exceeded, skip update")
 				if t.limiterMessageHandler != nil {
-					if err := t.limiterMessageHandler(ctx, update); err != nil {
-						log.WithError(err).Error("failed execute limiter message handler")
+					if err := safeCall(ctx, t.limiterMessageHandler, update); err != nil {
+						t.reportError(ctx, update, HandlerKindLimiter, err)
 					}
 				}
 
@@ -344,12 +467,10 @@ func (t *TelegramStateService[Action, Command, Callback]) Run(ctx context.Contex
 
 			log.Debug("success check rates by this user")
 
-			if _, ok = t.chatRequestChannels[chatID]; !ok {
-				t.chatRequestChannels[chatID] = make(chan *models.Update, 10)
-			}
+			queueKey := t.queueKey(chatID, update)
 
-			t.chatRequestChannels[chatID] <- update
-			t.processor.PutChat(chatID)
+			t.chatRequestChan(queueKey) <- update
+			t.processor.PutChat(queueKey)
 			t.processingQueueChan <- struct{}{}
 
 			log.Debug("update successfully queued for processing")
@@ -357,30 +478,125 @@ func (t *TelegramStateService[Action, Command, Callback]) Run(ctx context.Contex
 	}
 }
 
+type chatThread struct {
+	chatID   int64
+	threadID int
+}
+
+type queuedUpdate struct {
+	queueKey int64
+	update   *models.Update
+}
+
+// queueKey returns the id of the chat queue. With thread keys every chat topic gets its own id until its queue drains.
+func (t *TelegramStateService[Action, Command, Callback]) queueKey(chatID int64, update *models.Update) int64 {
+	if !t.threadKeys || chatID == 0 {
+		return chatID
+	}
+
+	return t.queueKeys.acquire(chatThread{chatID: chatID, threadID: UpdateThreadID(update)})
+}
+
+// releaseQueueKey forgets the chat topic queue once its last update is processed.
+func (t *TelegramStateService[Action, Command, Callback]) releaseQueueKey(queueKey int64) {
+	if !t.threadKeys || !t.queueKeys.release(queueKey) {
+		return
+	}
+
+	t.chatRequestMu.Lock()
+	delete(t.chatRequestChannels, queueKey)
+	t.chatRequestMu.Unlock()
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) chatRequestChan(queueKey int64) chan *models.Update {
+	t.chatRequestMu.Lock()
+	defer t.chatRequestMu.Unlock()
+
+	chatRequestChan, ok := t.chatRequestChannels[queueKey]
+
+	if !ok {
+		chatRequestChan = make(chan *models.Update, 10)
+		t.chatRequestChannels[queueKey] = chatRequestChan
+	}
+
+	return chatRequestChan
+}
+
+// threadQueueKeys assigns queue ids to chat topics. Ids are never reused, so a topic whose queue drained
+// gets a new id and the map does not grow with every topic ever seen.
+type threadQueueKeys struct {
+	mu      sync.Mutex
+	lastKey int64
+	keys    map[chatThread]int64
+	threads map[int64]chatThread
+	pending map[int64]int
+}
+
+// acquire returns the queue id of the topic for one more queued update.
+func (k *threadQueueKeys) acquire(thread chatThread) int64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.keys == nil {
+		k.keys = make(map[chatThread]int64)
+		k.threads = make(map[int64]chatThread)
+		k.pending = make(map[int64]int)
+	}
+
+	queueKey, ok := k.keys[thread]
+
+	if !ok {
+		k.lastKey++
+		queueKey = k.lastKey
+		k.keys[thread] = queueKey
+		k.threads[queueKey] = thread
+	}
+
+	k.pending[queueKey]++
+
+	return queueKey
+}
+
+// release marks one update of the queue processed and reports whether the queue drained.
+func (k *threadQueueKeys) release(queueKey int64) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.pending[queueKey]--; k.pending[queueKey] > 0 {
+		return false
+	}
+
+	delete(k.keys, k.threads[queueKey])
+	delete(k.threads, queueKey)
+	delete(k.pending, queueKey)
+
+	return true
+}
+
 func (t *TelegramStateService[Action, Command, Callback]) startConsumeQueueChan(ctx context.Context) {
-	processingChan := make(chan *models.Update, t.workersCount)
+	processingChan := make(chan queuedUpdate, t.workersCount)
 	omitChatIdsChan := make(chan int64, t.workersCount)
 
 	go t.processor.Run(ctx, omitChatIdsChan)
 
-	for i := range t.workersCount {
+	workersCount := t.workersCount
+
+	if t.workerPool != nil {
+		go t.submitToPool(ctx, processingChan, omitChatIdsChan)
+		workersCount = 0
+	}
+
+	for i := range workersCount {
 		go func(workerId int) {
 			for {
 				select {
 				case <-ctx.Done():
 					return
 
-				case update := <-processingChan:
+				case queued := <-processingChan:
 					logrus.WithField("workerID", workerId).Debug("start processing update")
-					t.handleUpdate(ctx, update)
+					t.processUpdate(ctx, queued, omitChatIdsChan)
 					logrus.WithField("workerID", workerId).Debug("finished processing update")
-
-					chat := UpdateChat(update)
-					if chat == nil {
-						continue
-					}
-
-					omitChatIdsChan <- chat.ID
 				}
 			}
 		}(i)
@@ -409,7 +625,9 @@ func (t *TelegramStateService[Action, Command, Callback]) startConsumeQueueChan(
 
 			log.Debug("try get update from chat queue")
 
+			t.chatRequestMu.RLock()
 			chatRequestChan, ok := t.chatRequestChannels[chatID]
+			t.chatRequestMu.RUnlock()
 
 			if !ok {
 				ticker.Reset(time.Millisecond * 100)
@@ -425,7 +643,7 @@ func (t *TelegramStateService[Action, Command, Callback]) startConsumeQueueChan(
 
 			log.WithField("updateID", update.ID).Debug("add update to worker processing queue")
 
-			processingChan <- update
+			processingChan <- queuedUpdate{queueKey: chatID, update: update}
 			ticker.Reset(time.Millisecond * 100)
 
 		case <-ticker.C:
@@ -444,7 +662,9 @@ func (t *TelegramStateService[Action, Command, Callback]) startConsumeQueueChan(
 
 			log.Debug("try get update from chat queue")
 
+			t.chatRequestMu.RLock()
 			chatRequestChan, ok := t.chatRequestChannels[chatID]
+			t.chatRequestMu.RUnlock()
 
 			if !ok {
 				ticker.Reset(time.Millisecond * 100)
@@ -460,25 +680,63 @@ func (t *TelegramStateService[Action, Command, Callback]) startConsumeQueueChan(
 
 			log.WithField("updateID", update.ID).Debug("add update to worker processing queue")
 
-			processingChan <- update
+			processingChan <- queuedUpdate{queueKey: chatID, update: update}
 			ticker.Reset(time.Millisecond * 100)
 		}
 	}
 }
 
+func (t *TelegramStateService[Action, Command, Callback]) submitToPool(ctx context.Context, processingChan <-chan queuedUpdate, omitChatIdsChan chan<- int64) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case queued := <-processingChan:
+			t.workerPool.Submit(ctx, func() {
+				t.processUpdate(ctx, queued, omitChatIdsChan)
+			})
+		}
+	}
+}
+
+// processUpdate handles the queued update and releases its chat queue even when the handler panics.
+func (t *TelegramStateService[Action, Command, Callback]) processUpdate(ctx context.Context, queued queuedUpdate, omitChatIdsChan chan<- int64) {
+	defer func() {
+		if queued.queueKey != 0 {
+			omitChatIdsChan <- queued.queueKey
+			t.releaseQueueKey(queued.queueKey)
+		}
+	}()
+
+	defer t.recoverUpdate(ctx, queued.update)
+
+	t.handleUpdate(ctx, queued.update)
+}
+
 func (t *TelegramStateService[Action, Command, Callback]) handleUpdate(ctx context.Context, update *models.Update) {
 	log := logrus.WithFields(logrus.Fields{
 		"updateID": update.ID,
 	})
 
+	if t.threadKeys {
+		ctx = storage.WithThread(ctx, UpdateThreadID(update))
+	}
+
+	ctx = storage.WithActionStorage(ctx, t.actionStorage)
+
+	if user := UpdateUser(update); user != nil && t.sessionStorage != nil {
+		ctx = storage.WithSession(ctx, t.sessionStorage, user.ID)
+	}
+
 	if update.Message != nil && update.Message.MigrateToChatID != 0 {
 		if t.chatMigrationHandler == nil {
 			return
 		}
 
 		log.Debug("handle chat migration event")
-		if err := t.chatMigrationHandler(ctx, update); err != nil {
-			log.WithError(err).Error("failed execute chat migration handler")
+		if err := safeCall(ctx, t.chatMigrationHandler, update); err != nil {
+			t.reportError(ctx, update, HandlerKindMigration, err)
 		}
 		return
 	}
@@ -488,8 +746,8 @@ func (t *TelegramStateService[Action, Command, Callback]) handleUpdate(ctx conte
 			return
 		}
 		log.Debug("handle my chat member event")
-		if err := t.myChatMemberHandler(ctx, update); err != nil {
-			log.WithError(err).Error("failed handle my chat member event")
+		if err := safeCall(ctx, t.myChatMemberHandler, update); err != nil {
+			t.reportError(ctx, update, HandlerKindMyChatMember, err)
 		}
 		return
 	}
@@ -499,8 +757,8 @@ func (t *TelegramStateService[Action, Command, Callback]) handleUpdate(ctx conte
 			return
 		}
 		log.Debug("handle chat member event")
-		if err := t.chatMemberHandler(ctx, update); err != nil {
-			log.WithError(err).Error("failed handle chat member event")
+		if err := safeCall(ctx, t.chatMemberHandler, update); err != nil {
+			t.reportError(ctx, update, HandlerKindChatMember, err)
 		}
 		return
 	}
@@ -510,44 +768,98 @@ func (t *TelegramStateService[Action, Command, Callback]) handleUpdate(ctx conte
 			return
 		}
 		log.Debug("handle chat member event")
-		if err := t.chatJoinRequestHandler(ctx, update); err != nil {
-			log.WithError(err).Error("failed handle chat member event")
+		if err := safeCall(ctx, t.chatJoinRequestHandler, update); err != nil {
+			t.reportError(ctx, update, HandlerKindChatJoinRequest, err)
 		}
 		return
 	}
 
-	if t.middlewareFunc != nil {
-		log.Debug("try call middleware")
-		var isSuccess bool
+	// payment messages bypass the middleware, so the payment is never lost
+	if update.Message != nil && (update.Message.SuccessfulPayment != nil || update.Message.RefundedPayment != nil) {
+		t.handlePaymentMessage(ctx, update)
+		return
+	}
 
-		ctx, isSuccess = t.middlewareFunc(ctx, update)
+	route := Chain(func(ctx context.Context, update *models.Update) error {
+		if update.Message != nil {
+			log.Debug("handle message event")
+			err := t.handleMessage(ctx, update)
+			t.refreshActionTimeout(ctx, update)
+			return err
+		}
 
-		if !isSuccess {
-			log.Debug("failed call middleware")
-			return
+		if update.CallbackQuery != nil {
+			log.Debug("handle callback event")
+			err := t.handleCallback(ctx, update)
+
+			if answerErr := t.telegramClient.AnswerCallbackQuery(ctx, update.CallbackQuery.ID); answerErr != nil {
+				log.WithError(answerErr).Error("failed answer callback query")
+			}
+
+			t.refreshActionTimeout(ctx, update)
+
+			return err
 		}
 
-		log.Debug("middleware called successfully")
+		return nil
+	}, t.middlewares...)
+
+	// handler errors pass through the middlewares and are reported once here with the kind of the handler
+	if err := safeCall(ctx, route, update); err != nil {
+		kind, err := handlerErrorKind(err)
+		t.reportError(ctx, update, kind, err)
 	}
+}
 
-	if update.Message != nil {
-		log.Debug("handle message event")
-		t.handleMessage(ctx, update)
+func isChatlessUpdate(update *models.Update) bool {
+	return update.InlineQuery != nil || update.ChosenInlineResult != nil ||
+		update.ShippingQuery != nil || update.PreCheckoutQuery != nil
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) handleChatlessUpdate(ctx context.Context, update *models.Update) {
+	select {
+	case <-ctx.Done():
 		return
+	case t.chatlessSemaphore <- struct{}{}:
 	}
 
-	if update.CallbackQuery != nil {
-		log.Debug("handle callback event")
-		t.handleCallback(ctx, update)
+	defer func() { <-t.chatlessSemaphore }()
+	defer t.recoverUpdate(ctx, update)
 
-		if err := t.telegramClient.AnswerCallbackQuery(ctx, update.CallbackQuery.ID); err != nil {
-			log.WithError(err).Error("failed answer callback query")
-		}
+	switch {
+	case update.ShippingQuery != nil:
+		t.handleShippingQuery(ctx, update)
+	case update.PreCheckoutQuery != nil:
+		t.handlePreCheckoutQuery(ctx, update)
+	default:
+		t.handleInlineUpdate(ctx, update)
+	}
+}
+
+func (t *TelegramStateService[Action, Command, Callback]) handleInlineUpdate(ctx context.Context, update *models.Update) {
+	handler := t.inlineQueryHandler
+
+	if update.ChosenInlineResult != nil {
+		handler = t.chosenInlineResultHandler
+	}
+
+	if handler == nil {
 		return
 	}
+
+	logrus.WithField("updateID", update.ID).Debug("handle inline event")
+
+	inline := func(ctx context.Context, update *models.Update) error {
+		return withHandlerKind(HandlerKindInline, safeCall(ctx, handler, update))
+	}
+
+	if err := safeCall(ctx, Chain(inline, t.middlewares...), update); err != nil {
+		kind, err := handlerErrorKind(err)
+		t.reportError(ctx, update, kind, err)
+	}
 }
 
-func (t *TelegramStateService[Action, Command, Callback]) handleCallback(ctx context.Context, update *models.Update) {
+func (t *TelegramStateService[Action, Command, Callback]) handleCallback(ctx context.Context, update *models.Update) error {
 	user := UpdateUser(update)
 	chat := UpdateChat(update)
 	var userID, chatID int64
@@ -576,39 +888,36 @@ func (t *TelegramStateService[Action, Command, Callback]) handleCallback(ctx con
 	if ok {
 		log.WithField("callback", callback).
 			Debug("event contains callback data, call handler")
-		err := callbackHandler.Handler(ctx, update)
 
-		if err != nil {
-			log.WithError(err).Error("failed handle callback event")
-		}
-		return
+		return withHandlerKind(HandlerKindCallback, t.runHandler(ctx, callbackHandler, update, func(ctx context.Context, update *models.Update) error {
+			return t.callHandler(ctx, chatID, callbackHandler, t.callbackChatActions[callback], update)
+		}))
 	}
 
 	action, err := t.actionStorage.GetAction(ctx, userID)
 
 	if err != nil {
 		log.WithError(err).Error("failed to get action by user")
-		return
+		return nil
 	}
 
 	actionHandler, ok := t.actionHandler[Action(action)]
 
 	if !ok {
-		log.Error("action handler not found")
-		return
+		return t.handleDefault(ctx, chatID, update, log)
 	}
 
 	log.WithField("action", action).
 		Debug("event contains action data, call handler")
 
-	err = actionHandler.Handler(ctx, update)
-
-	if err != nil {
-		log.WithError(err).Error("failed handle action callback event")
-	}
+	return withHandlerKind(HandlerKindAction, t.runHandler(ctx, actionHandler, update, func(ctx context.Context, update *models.Update) error {
+		return t.callHandler(ctx, chatID, actionHandler, t.actionChatActions[Action(action)], update)
+	}))
 }
 
-func (t *TelegramStateService[Action, Command, Callback]) handleMessage(ctx context.Context, update *models.Update) {
+// handleMessage routes the message by command, exact or localized text, action of the user, regexp,
+// content type and chat type, in this order. Messages not matched by any route go to the default handler.
+func (t *TelegramStateService[Action, Command, Callback]) handleMessage(ctx context.Context, update *models.Update) error {
 	user := UpdateUser(update)
 	chat := UpdateChat(update)
 	var userID, chatID int64
@@ -631,63 +940,67 @@ func (t *TelegramStateService[Action, Command, Callback]) handleMessage(ctx cont
 	cmdHandler, ok := t.commandHandler[Command(cmd)]
 
 	if ok {
-		log.WithField("command", cmd).Debug("try process validations before call handler")
+		return withHandlerKind(HandlerKindCommand, t.runHandler(ctx, cmdHandler, update, func(ctx context.Context, update *models.Update) error {
+			log.WithField("command", cmd).Debug("try process validations before call handler")
 
-		if err := t.processValidation(ctx, chatID, update, cmdHandler.MessageValidators, log, 0); err != nil {
-			return
-		}
+			if err := t.processValidation(ctx, chatID, update, cmdHandler.MessageValidators, log, 0); err != nil {
+				return nil
+			}
 
-		log.WithField("command", cmd).Debug("validations processed, call handler")
+			log.WithField("command", cmd).Debug("validations processed, call handler")
 
-		err := cmdHandler.Handler(ctx, update)
+			return t.callHandler(ctx, chatID, cmdHandler, t.commandChatActions[Command(cmd)], update)
+		}))
+	}
 
-		if err != nil {
-			log.WithError(err).Error("failed handle command event")
-		}
-		return
+	if textHandler, ok := t.matchTextRoute(update.Message); ok {
+		log.Debug("event matches text route, call handler")
+		return t.handleRoute(ctx, chatID, update, textHandler, log)
 	}
 
 	action, err := t.actionStorage.GetAction(ctx, userID)
 
 	if err != nil {
-		return
+		return nil
 	}
 
 	actionHandler, ok := t.actionHandler[Action(action)]
 
 	if !ok {
-		log.Error("action handler not found")
-		return
+		if routeHandler, ok := t.matchMessageRoute(update.Message); ok {
+			log.Debug("event matches message route, call handler")
+			return t.handleRoute(ctx, chatID, update, routeHandler, log)
+		}
+
+		return t.handleDefault(ctx, chatID, update, log)
 	}
 
 	isCancel := MessageIsCommand(update.Message) && MessageCommand(update.Message) == "cancel"
 
-	if isCancel {
-		log.WithField("action", action).
-			Debug("event is cancel command, call handler")
+	return withHandlerKind(HandlerKindAction, t.runHandler(ctx, actionHandler, update, func(ctx context.Context, update *models.Update) error {
+		if isCancel {
+			log.WithField("action", action).
+				Debug("event is cancel command, call handler")
 
-		err = actionHandler.Handler(ctx, update)
-
-		if err != nil {
-			log.WithError(err).Error("failed handle cancel command event")
+			return t.callHandler(ctx, chatID, actionHandler, t.actionChatActions[Action(action)], update)
 		}
 
-		return
-	}
-
-	log.WithField("action", action).Debug("try process validations before call handler")
+		log.WithField("action", action).Debug("try process validations before call handler")
 
-	if err = t.processValidation(ctx, chatID, update, actionHandler.MessageValidators, log, Action(action)); err != nil {
-		return
-	}
+		if err := t.processValidation(ctx, chatID, update, actionHandler.MessageValidators, log, Action(action)); err != nil {
+			return nil
+		}
 
-	log.WithField("action", action).Debug("validations processed, call handler")
+		log.WithField("action", action).Debug("validations processed, call handler")
 
-	err = actionHandler.Handler(ctx, update)
+		return t.callHandler(ctx, chatID, actionHandler, t.actionChatActions[Action(action)], update)
+	}))
+}
 
-	if err != nil {
-		log.WithError(err).Error("failed handle event")
-	}
+// runHandler runs the validation and the call of the handler wrapped with the middlewares it was registered with,
+// so the middlewares of groups run before validators.
+func (t *TelegramStateService[Action, Command, Callback]) runHandler(ctx context.Context, info HandlerInfo, update *models.Update, call HandlerFunc) error {
+	return Chain(call, info.Middlewares...)(ctx, update)
 }
 
 func (t *TelegramStateService[Action, Command, Callback]) processValidation(ctx context.Context, chatID int64, update *models.Update, validators []ValidatorFunc, log *logrus.Entry, action Action) error {
@@ -772,6 +1085,26 @@ func UpdateChat(u *models.Update) *models.Chat {
 	return nil
 }
 
+// UpdateThreadID returns the forum topic of the update message, zero for messages outside of topics.
+func UpdateThreadID(u *models.Update) int {
+	var message *models.Message
+
+	switch {
+	case u.Message != nil:
+		message = u.Message
+	case u.EditedMessage != nil:
+		message = u.EditedMessage
+	case u.CallbackQuery != nil:
+		message = u.CallbackQuery.Message.Message
+	}
+
+	if message == nil || !message.IsTopicMessage {
+		return 0
+	}
+
+	return message.MessageThreadID
+}
+
 // UpdateUser extracts the user associated with the update.
 func UpdateUser(u *models.Update) *models.User {
 	switch {
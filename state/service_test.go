@@ -0,0 +1,59 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+)
+
+func TestUseSessionStorageWrapsActions(t *testing.T) {
+	actions := newTestActionStorage()
+	sessions := storage.NewInMemorySessionStorage(nil, 0)
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, actions, nil, nil, nil).
+		UseSessionStorage(sessions).
+		UseSessionStorage(sessions)
+
+	wrapped, ok := service.actionStorage.(*storage.SessionActionStorage)
+
+	if !ok || wrapped.UserActionStorage != actions {
+		t.Fatalf("expected action storage wrapped once, got %T", service.actionStorage)
+	}
+}
+
+func TestHandlerActionResetClearsSession(t *testing.T) {
+	telegramClient, _ := newTestClient(t, func(string) any { return nil })
+	cache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1000, BufferItems: 64})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := storage.NewInMemorySessionStorage(cache, 0)
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, newTestActionStorage(), nil, telegramClient, nil).
+		UseSessionStorage(sessions).
+		RegisterCommandHandler("done", func(ctx context.Context, update *models.Update) error {
+			return storage.ActionStorageFromContext(ctx).SaveAction(ctx, update.Message.From.ID, 0)
+		})
+
+	ctx := context.Background()
+	_ = sessions.SaveSession(ctx, 7, []byte(`{}`))
+
+	service.handleUpdate(ctx, &models.Update{Message: &models.Message{
+		Text:     "/done",
+		Chat:     models.Chat{ID: 7},
+		From:     &models.User{ID: 7},
+		Entities: []models.MessageEntity{{Type: models.MessageEntityTypeBotCommand, Length: 5}},
+	}})
+
+	if _, err := sessions.GetSession(ctx, 7); !errors.Is(err, domain.ErrorSessionNotFound) {
+		t.Fatalf("expected session cleared by the handler moving to action 0, got %v", err)
+	}
+}
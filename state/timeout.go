@@ -0,0 +1,157 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/scheduler"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const actionExpiryJobHandler = "action-expiry"
+
+// ActionExpiryHandlerFunc is called after the user was inactive in the action longer than its timeout.
+// The action is already reset to 0 when the handler is called.
+type ActionExpiryHandlerFunc func(ctx context.Context, chatID, userID int64) error
+
+type actionTimeout struct {
+	timeout       time.Duration
+	expiryHandler ActionExpiryHandlerFunc
+}
+
+type actionExpiry struct {
+	UserID   int64 `json:"user_id"`
+	ThreadID int   `json:"thread_id,omitempty"`
+	Action   int   `json:"action"`
+}
+
+// EnableActionTimeouts resets actions of inactive users by jobs of the scheduler, so timeouts survive restarts
+// and are handled by one of the replicas. The scheduler has to run on every replica. Bots sharing the scheduler
+// need distinct names set with SetBotName before the call.
+func (t *TelegramStateService[Action, Command, Callback]) EnableActionTimeouts(s *scheduler.SchedulerService) *TelegramStateService[Action, Command, Callback] {
+	t.actionScheduler = s
+	s.RegisterJobHandler(actionExpiryHandlerName(t.botName), t.handleActionExpiry)
+
+	return t
+}
+
+// SetActionTimeout resets the action after the user sent no updates for the timeout and calls the optional
+// expiry handler, e.g. to tell the user the session is expired. Requires EnableActionTimeouts, Run warns when
+// timeouts are set without it.
+func (t *TelegramStateService[Action, Command, Callback]) SetActionTimeout(action Action, timeout time.Duration, expiryHandler ActionExpiryHandlerFunc) *TelegramStateService[Action, Command, Callback] {
+	t.actionTimeouts[action] = actionTimeout{timeout: timeout, expiryHandler: expiryHandler}
+
+	return t
+}
+
+// warnDisabledActionTimeouts reports timeouts that are never applied, as EnableActionTimeouts was not called.
+func (t *TelegramStateService[Action, Command, Callback]) warnDisabledActionTimeouts() {
+	if len(t.actionTimeouts) > 0 && t.actionScheduler == nil {
+		logrus.Warn("action timeouts are set, but EnableActionTimeouts was not called, actions will not expire")
+	}
+}
+
+// actionExpiryHandlerName includes the bot name, so bots sharing the scheduler reset actions in their own storages.
+func actionExpiryHandlerName(botName string) string {
+	return fmt.Sprintf("%s:%s", actionExpiryJobHandler, botName)
+}
+
+func actionExpiryJobID(botName string, userID int64, threadID int) string {
+	return fmt.Sprintf("%s:%s:%d:%d", actionExpiryJobHandler, botName, userID, threadID)
+}
+
+// refreshActionTimeout schedules the expiry of the action the user is in after the update, the job of
+// the previous update is replaced. Jobs of actions without timeout are left to expire as stale.
+func (t *TelegramStateService[Action, Command, Callback]) refreshActionTimeout(ctx context.Context, update *models.Update) {
+	if t.actionScheduler == nil || len(t.actionTimeouts) == 0 {
+		return
+	}
+
+	user := UpdateUser(update)
+	chat := UpdateChat(update)
+
+	if user == nil || chat == nil {
+		return
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"updateID": update.ID,
+		"userID":   user.ID,
+	})
+
+	action, err := t.actionStorage.GetAction(ctx, user.ID)
+
+	if err != nil {
+		log.WithError(err).Debug("failed to get action by user")
+		return
+	}
+
+	timeout, ok := t.actionTimeouts[Action(action)]
+
+	if !ok {
+		return
+	}
+
+	threadID := storage.ThreadFromContext(ctx)
+
+	payload, err := json.Marshal(actionExpiry{UserID: user.ID, ThreadID: threadID, Action: action})
+
+	if err != nil {
+		log.WithError(err).Error("failed encode action expiry")
+		return
+	}
+
+	_, err = t.actionScheduler.ScheduleHandler(ctx, actionExpiryJobID(t.botName, user.ID, threadID),
+		time.Now().Add(timeout.timeout), chat.ID, actionExpiryHandlerName(t.botName), string(payload))
+
+	if err != nil {
+		log.WithError(err).Error("failed schedule action expiry")
+	}
+}
+
+// handleActionExpiry resets the action of the job unless the user has already left it. The action is compared
+// and reset atomically when the storage implements storage.ActionComparer, so a message moving the user to another
+// action at the same moment is not lost.
+func (t *TelegramStateService[Action, Command, Callback]) handleActionExpiry(ctx context.Context, job *storage.ScheduledJob) error {
+	var expiry actionExpiry
+
+	if err := json.Unmarshal([]byte(job.Payload), &expiry); err != nil {
+		return err
+	}
+
+	ctx = storage.WithThread(ctx, expiry.ThreadID)
+	ctx = context.WithValue(ctx, botCtxKey{}, &BotInstance{Name: t.botName, Client: t.telegramClient})
+	ctx = storage.WithActionStorage(ctx, t.actionStorage)
+
+	if t.sessionStorage != nil {
+		ctx = storage.WithSession(ctx, t.sessionStorage, expiry.UserID)
+	}
+
+	expired, err := storage.CompareAndSaveAction(ctx, t.actionStorage, expiry.UserID, expiry.Action, 0)
+
+	if err != nil || !expired {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID": expiry.UserID,
+		"action": expiry.Action,
+	}).Debug("action expired")
+
+	timeout, ok := t.actionTimeouts[Action(expiry.Action)]
+
+	if !ok || timeout.expiryHandler == nil {
+		return nil
+	}
+
+	// the action is reset, so a failed handler is not retried
+	if err = timeout.expiryHandler(ctx, job.ChatID, expiry.UserID); err != nil {
+		logrus.WithField("userID", expiry.UserID).WithError(err).Error("failed handle action expiry")
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/config"
+	"github.com/nejkit/telegram-bot-core/v2/scheduler"
+	"github.com/nejkit/telegram-bot-core/v2/storage"
+)
+
+// testActionStorage keeps actions by user and forum topic of the context.
+type testActionStorage struct {
+	actions map[chatThread]int
+}
+
+func newTestActionStorage() *testActionStorage {
+	return &testActionStorage{actions: make(map[chatThread]int)}
+}
+
+func (s *testActionStorage) key(ctx context.Context, userID int64) chatThread {
+	return chatThread{chatID: userID, threadID: storage.ThreadFromContext(ctx)}
+}
+
+func (s *testActionStorage) SaveAction(ctx context.Context, userID int64, action int) error {
+	s.actions[s.key(ctx, userID)] = action
+	return nil
+}
+
+func (s *testActionStorage) GetAction(ctx context.Context, userID int64) (int, error) {
+	return s.actions[s.key(ctx, userID)], nil
+}
+
+func (s *testActionStorage) SaveActionWithRollback(ctx context.Context, userID int64, action int) (func(err error) error, error) {
+	return func(error) error { return nil }, s.SaveAction(ctx, userID, action)
+}
+
+func (s *testActionStorage) CompareAndSaveAction(ctx context.Context, userID int64, expected, action int) (bool, error) {
+	if s.actions[s.key(ctx, userID)] != expected {
+		return false, nil
+	}
+
+	return true, s.SaveAction(ctx, userID, action)
+}
+
+func TestHandleActionExpiry(t *testing.T) {
+	actions := newTestActionStorage()
+	actions.actions[chatThread{chatID: 1}] = 2
+	expired := 0
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, actions, nil, nil, nil).
+		SetActionTimeout(2, time.Minute, func(_ context.Context, chatID, userID int64) error {
+			expired++
+			return nil
+		})
+
+	job := &storage.ScheduledJob{ChatID: 1, Payload: `{"user_id":1,"action":2}`}
+
+	if err := service.handleActionExpiry(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+
+	if actions.actions[chatThread{chatID: 1}] != 0 || expired != 1 {
+		t.Fatalf("expected action to be reset and handler called, got action %d, calls %d", actions.actions[chatThread{chatID: 1}], expired)
+	}
+
+	actions.actions[chatThread{chatID: 1}] = 3
+
+	if err := service.handleActionExpiry(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+
+	if actions.actions[chatThread{chatID: 1}] != 3 || expired != 1 {
+		t.Fatal("expected expiry of the left action to be skipped")
+	}
+}
+
+func TestActionExpiryInTopic(t *testing.T) {
+	actions := newTestActionStorage()
+	actions.actions[chatThread{chatID: 1}] = 2
+	actions.actions[chatThread{chatID: 1, threadID: 5}] = 2
+
+	schedulerService := scheduler.NewSchedulerService(config.SchedulerConfig{}, storage.NewInMemorySchedulerStorage(), nil)
+
+	var expiredThread int
+
+	service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, actions, nil, nil, nil).
+		EnableThreadKeys().
+		EnableActionTimeouts(schedulerService).
+		SetActionTimeout(2, time.Minute, func(ctx context.Context, _, _ int64) error {
+			expiredThread = storage.ThreadFromContext(ctx)
+			return nil
+		})
+
+	update := &models.Update{Message: &models.Message{
+		From:            &models.User{ID: 1},
+		Chat:            models.Chat{ID: -100},
+		IsTopicMessage:  true,
+		MessageThreadID: 5,
+	}}
+
+	service.refreshActionTimeout(storage.WithThread(context.Background(), 5), update)
+
+	job, err := schedulerService.GetJob(context.Background(), actionExpiryJobID(service.botName, 1, 5))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = service.handleActionExpiry(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+
+	if actions.actions[chatThread{chatID: 1, threadID: 5}] != 0 || actions.actions[chatThread{chatID: 1}] != 2 || expiredThread != 5 {
+		t.Fatalf("expected only the action of the topic to expire, got %v, handler thread %d", actions.actions, expiredThread)
+	}
+}
+
+func TestActionTimeoutsOfBotsSharingScheduler(t *testing.T) {
+	schedulerService := scheduler.NewSchedulerService(config.SchedulerConfig{PollInterval: 5 * time.Millisecond}, storage.NewInMemorySchedulerStorage(), nil)
+	expired := make(chan string, 2)
+
+	newBot := func(name string) (*TelegramStateService[int, string, string], *testActionStorage) {
+		actions := newTestActionStorage()
+		actions.actions[chatThread{chatID: 1}] = 2
+
+		service := NewTelegramStateService[int, string, string](config.TelegramConfig{WorkersCount: 1}, actions, nil, nil, nil).
+			SetBotName(name).
+			EnableActionTimeouts(schedulerService).
+			SetActionTimeout(2, time.Millisecond, func(context.Context, int64, int64) error {
+				expired <- name
+				return nil
+			})
+
+		return service, actions
+	}
+
+	first, firstActions := newBot("first")
+	_, secondActions := newBot("second")
+
+	first.refreshActionTimeout(context.Background(), &models.Update{Message: &models.Message{From: &models.User{ID: 1}, Chat: models.Chat{ID: 1}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		schedulerService.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case name := <-expired:
+		if name != "first" {
+			t.Errorf("expected the action of the first bot to expire, got %s", name)
+		}
+	case <-time.After(time.Second):
+		t.Error("action did not expire")
+	}
+
+	cancel()
+	<-stopped
+
+	if firstActions.actions[chatThread{chatID: 1}] != 0 || secondActions.actions[chatThread{chatID: 1}] != 2 {
+		t.Fatalf("expected only the action of the first bot reset, got %v and %v", firstActions.actions, secondActions.actions)
+	}
+}
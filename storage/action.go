@@ -4,12 +4,44 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+
 	"github.com/dgraph-io/ristretto"
 	"github.com/redis/go-redis/v9"
 )
 
-func (s *RedisUserActionStorage[T]) getUserActionsKey(userID int64) string {
-	return fmt.Sprintf("%s:user:action:%d", s.botInstancePrefix, userID)
+// compareAndSaveActionScript replaces the action if it equals the expected one, zero action deletes the key.
+var compareAndSaveActionScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current ~= tonumber(ARGV[1]) then
+	return 0
+end
+if tonumber(ARGV[2]) == 0 then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// CompareAndSaveAction saves the action only if the user is still in the expected action. Storages not implementing
+// ActionComparer are read and saved by separate calls, so an action saved between them may be overwritten.
+func CompareAndSaveAction(ctx context.Context, actions UserActionStorage, userID int64, expected, action int) (bool, error) {
+	if comparer, ok := actions.(ActionComparer); ok {
+		return comparer.CompareAndSaveAction(ctx, userID, expected, action)
+	}
+
+	current, err := actions.GetAction(ctx, userID)
+
+	if err != nil || current != expected {
+		return false, err
+	}
+
+	return true, actions.SaveAction(ctx, userID, action)
+}
+
+func (s *RedisUserActionStorage[T]) getUserActionsKey(ctx context.Context, userID int64) string {
+	return fmt.Sprintf("%s:user:action:%s", s.botInstancePrefix, chatKey(ctx, userID))
 }
 
 type UserAction interface {
@@ -30,14 +62,14 @@ func NewRedisUserActionStorage[T UserAction](
 
 func (s *RedisUserActionStorage[T]) SaveAction(ctx context.Context, userID int64, action T) error {
 	if action == 0 {
-		return s.client.Del(ctx, s.getUserActionsKey(userID)).Err()
+		return s.client.Del(ctx, s.getUserActionsKey(ctx, userID)).Err()
 	}
 
-	return s.client.Set(ctx, s.getUserActionsKey(userID), int(action), 0).Err()
+	return s.client.Set(ctx, s.getUserActionsKey(ctx, userID), int(action), 0).Err()
 }
 
 func (s *RedisUserActionStorage[T]) GetAction(ctx context.Context, userID int64) (T, error) {
-	action, err := s.client.Get(ctx, s.getUserActionsKey(userID)).Int()
+	action, err := s.client.Get(ctx, s.getUserActionsKey(ctx, userID)).Int()
 
 	if errors.Is(err, redis.Nil) {
 		return 0, nil
@@ -73,28 +105,41 @@ func (s *RedisUserActionStorage[T]) SaveActionWithRollback(ctx context.Context,
 	return rollbackFunc, nil
 }
 
+func (s *RedisUserActionStorage[T]) CompareAndSaveAction(ctx context.Context, userID int64, expected, action T) (bool, error) {
+	return compareAndSaveActionScript.Run(ctx, s.client, []string{s.getUserActionsKey(ctx, userID)}, int(expected), int(action)).Bool()
+}
+
 type InMemoryUserActionStorage[T UserAction] struct {
 	client *ristretto.Cache
+	// mu makes the compare and save atomic, the cache has no such operation
+	mu sync.Mutex
 }
 
 func NewInMemoryUserActionStorage[action UserAction](client *ristretto.Cache) *InMemoryUserActionStorage[action] {
 	return &InMemoryUserActionStorage[action]{client: client}
 }
 
-func (i *InMemoryUserActionStorage[T]) getUserActionsKey(userID int64) string {
-	return fmt.Sprintf("user:action:%d", userID)
+func (i *InMemoryUserActionStorage[T]) getUserActionsKey(ctx context.Context, userID int64) string {
+	return fmt.Sprintf("user:action:%s", chatKey(ctx, userID))
+}
+
+func (i *InMemoryUserActionStorage[T]) SaveAction(ctx context.Context, userID int64, action T) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.saveAction(ctx, userID, action)
 }
 
-func (i *InMemoryUserActionStorage[T]) SaveAction(_ context.Context, userID int64, action T) error {
-	if ok := i.client.Set(i.getUserActionsKey(userID), int(action), 0); !ok {
+func (i *InMemoryUserActionStorage[T]) saveAction(ctx context.Context, userID int64, action T) error {
+	if ok := i.client.Set(i.getUserActionsKey(ctx, userID), int(action), 0); !ok {
 		return errors.New("failed to save action")
 	}
 
 	return nil
 }
 
-func (i *InMemoryUserActionStorage[T]) GetAction(_ context.Context, userID int64) (action T, err error) {
-	data, ok := i.client.Get(i.getUserActionsKey(userID))
+func (i *InMemoryUserActionStorage[T]) GetAction(ctx context.Context, userID int64) (action T, err error) {
+	data, ok := i.client.Get(i.getUserActionsKey(ctx, userID))
 
 	if !ok {
 		return 0, errors.New("failed to get action")
@@ -116,3 +161,20 @@ func (i *InMemoryUserActionStorage[T]) SaveActionWithRollback(ctx context.Contex
 
 	return rollback, i.SaveAction(ctx, userID, action)
 }
+
+func (i *InMemoryUserActionStorage[T]) CompareAndSaveAction(ctx context.Context, userID int64, expected, action T) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	current, err := i.GetAction(ctx, userID)
+
+	if err != nil {
+		current = 0
+	}
+
+	if current != expected {
+		return false, nil
+	}
+
+	return true, i.saveAction(ctx, userID, action)
+}
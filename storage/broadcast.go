@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type BroadcastStatus string
+
+const (
+	BroadcastStatusRunning   BroadcastStatus = "running"
+	BroadcastStatusPaused    BroadcastStatus = "paused"
+	BroadcastStatusCancelled BroadcastStatus = "cancelled"
+	BroadcastStatusCompleted BroadcastStatus = "completed"
+	BroadcastStatusFailed    BroadcastStatus = "failed"
+)
+
+type BroadcastMediaType string
+
+const (
+	BroadcastMediaPhoto     BroadcastMediaType = "photo"
+	BroadcastMediaVideo     BroadcastMediaType = "video"
+	BroadcastMediaAnimation BroadcastMediaType = "animation"
+	BroadcastMediaDocument  BroadcastMediaType = "document"
+	BroadcastMediaAudio     BroadcastMediaType = "audio"
+	BroadcastMediaVoice     BroadcastMediaType = "voice"
+)
+
+// BroadcastTemplate describes the message sent to every recipient. When LocaleKey is set the text is
+// localized with recipient language, LocalizeKeyboard treats button texts as localization keys as well.
+type BroadcastTemplate struct {
+	Text             string                       `json:"text,omitempty"`
+	LocaleKey        string                       `json:"locale_key,omitempty"`
+	LocaleArgs       []string                     `json:"locale_args,omitempty"`
+	ParseMode        models.ParseMode             `json:"parse_mode,omitempty"`
+	MediaType        BroadcastMediaType           `json:"media_type,omitempty"`
+	MediaFileID      string                       `json:"media_file_id,omitempty"`
+	InlineKeyboard   *models.InlineKeyboardMarkup `json:"inline_keyboard,omitempty"`
+	LocalizeKeyboard bool                         `json:"localize_keyboard,omitempty"`
+}
+
+type BroadcastInfo struct {
+	ID        string            `json:"id"`
+	Status    BroadcastStatus   `json:"status"`
+	Template  BroadcastTemplate `json:"template"`
+	Cursor    string            `json:"cursor,omitempty"`
+	Offset    int               `json:"offset,omitempty"`
+	Delivered int               `json:"delivered"`
+	Blocked   int               `json:"blocked"`
+	Failed    int               `json:"failed"`
+	LastError string            `json:"last_error,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	// Owner is the process running the broadcast, it saves HeartbeatAt while the broadcast runs.
+	Owner       string    `json:"owner,omitempty"`
+	HeartbeatAt time.Time `json:"heartbeat_at,omitempty"`
+}
+
+type RedisBroadcastStorage struct {
+	botInstancePrefix string
+	client            *redis.Client
+}
+
+func NewRedisBroadcastStorage(
+	botInstancePrefix string,
+	client *redis.Client,
+) *RedisBroadcastStorage {
+	return &RedisBroadcastStorage{botInstancePrefix: botInstancePrefix, client: client}
+}
+
+func (s *RedisBroadcastStorage) getBroadcastsKey() string {
+	return fmt.Sprintf("%s:broadcasts", s.botInstancePrefix)
+}
+
+func (s *RedisBroadcastStorage) SaveBroadcast(ctx context.Context, broadcast *BroadcastInfo) error {
+	payload, err := json.Marshal(broadcast)
+
+	if err != nil {
+		return err
+	}
+
+	return s.client.HSet(ctx, s.getBroadcastsKey(), broadcast.ID, payload).Err()
+}
+
+func (s *RedisBroadcastStorage) GetBroadcast(ctx context.Context, broadcastID string) (*BroadcastInfo, error) {
+	rawData, err := s.client.HGet(ctx, s.getBroadcastsKey(), broadcastID).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrorBroadcastNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var broadcast BroadcastInfo
+
+	if err = json.Unmarshal(rawData, &broadcast); err != nil {
+		return nil, err
+	}
+
+	return &broadcast, nil
+}
+
+func (s *RedisBroadcastStorage) GetBroadcasts(ctx context.Context) ([]BroadcastInfo, error) {
+	rawBroadcasts, err := s.client.HVals(ctx, s.getBroadcastsKey()).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	broadcasts := make([]BroadcastInfo, 0, len(rawBroadcasts))
+
+	for _, rawBroadcast := range rawBroadcasts {
+		var broadcast BroadcastInfo
+
+		if err := json.Unmarshal([]byte(rawBroadcast), &broadcast); err != nil {
+			return nil, err
+		}
+
+		broadcasts = append(broadcasts, broadcast)
+	}
+
+	return broadcasts, nil
+}
+
+func (s *RedisBroadcastStorage) DeleteBroadcast(ctx context.Context, broadcastID string) error {
+	return s.client.HDel(ctx, s.getBroadcastsKey(), broadcastID).Err()
+}
+
+type InMemoryBroadcastStorage struct {
+	mu         sync.RWMutex
+	broadcasts map[string]BroadcastInfo
+}
+
+func NewInMemoryBroadcastStorage() *InMemoryBroadcastStorage {
+	return &InMemoryBroadcastStorage{broadcasts: make(map[string]BroadcastInfo)}
+}
+
+func (i *InMemoryBroadcastStorage) SaveBroadcast(_ context.Context, broadcast *BroadcastInfo) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.broadcasts[broadcast.ID] = *broadcast
+
+	return nil
+}
+
+func (i *InMemoryBroadcastStorage) GetBroadcast(_ context.Context, broadcastID string) (*BroadcastInfo, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	broadcast, ok := i.broadcasts[broadcastID]
+
+	if !ok {
+		return nil, domain.ErrorBroadcastNotFound
+	}
+
+	return &broadcast, nil
+}
+
+func (i *InMemoryBroadcastStorage) GetBroadcasts(_ context.Context) ([]BroadcastInfo, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	broadcasts := make([]BroadcastInfo, 0, len(i.broadcasts))
+
+	for _, broadcast := range i.broadcasts {
+		broadcasts = append(broadcasts, broadcast)
+	}
+
+	return broadcasts, nil
+}
+
+func (i *InMemoryBroadcastStorage) DeleteBroadcast(_ context.Context, broadcastID string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.broadcasts, broadcastID)
+
+	return nil
+}
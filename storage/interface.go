@@ -11,6 +11,19 @@ type UserActionStorage interface {
 	SaveActionWithRollback(ctx context.Context, userID int64, action int) (func(err error) error, error)
 }
 
+// ActionComparer is implemented by action storages able to replace the action atomically, see CompareAndSaveAction.
+type ActionComparer interface {
+	// CompareAndSaveAction saves the action only if the user is still in the expected action.
+	CompareAndSaveAction(ctx context.Context, userID int64, expected, action int) (bool, error)
+}
+
+// SessionStorage keeps encoded session data of users, it is typed by Session.
+type SessionStorage interface {
+	SaveSession(ctx context.Context, userID int64, data []byte) error
+	GetSession(ctx context.Context, userID int64) ([]byte, error)
+	DeleteSession(ctx context.Context, userID int64) error
+}
+
 type InvitesStorage interface {
 	SaveInvite(ctx context.Context, deepLinkSecret string, fromUserID int64, expiration time.Duration) error
 	GetInvite(ctx context.Context, deepLinkSecret string) (int64, error)
@@ -28,3 +41,44 @@ type UserMessageStorage interface {
 	GetKeyboardInfo(ctx context.Context, chatID int64, messageID int) (*KeyboardInfo, error)
 	DeleteKeyboardInfo(ctx context.Context, chatID int64, messageID int) error
 }
+
+type OutboxStorage interface {
+	Enqueue(ctx context.Context, message *OutboxMessage) (bool, error)
+	ClaimChat(ctx context.Context, lease time.Duration) (chatID int64, ok bool, err error)
+	// ExtendChatLease keeps the claimed chat hidden from other workers while its message is delivered.
+	ExtendChatLease(ctx context.Context, chatID int64, lease time.Duration) error
+	PeekMessage(ctx context.Context, chatID int64) (*OutboxMessage, error)
+	CompleteMessage(ctx context.Context, message *OutboxMessage) error
+	RetryMessage(ctx context.Context, message *OutboxMessage) error
+	DeadLetter(ctx context.Context, message *OutboxMessage) error
+	GetDeadLetters(ctx context.Context, limit int) ([]OutboxMessage, error)
+	DeleteDeadLetters(ctx context.Context) error
+}
+
+type BroadcastStorage interface {
+	SaveBroadcast(ctx context.Context, broadcast *BroadcastInfo) error
+	GetBroadcast(ctx context.Context, broadcastID string) (*BroadcastInfo, error)
+	GetBroadcasts(ctx context.Context) ([]BroadcastInfo, error)
+	DeleteBroadcast(ctx context.Context, broadcastID string) error
+}
+
+type PaymentStorage interface {
+	SavePayment(ctx context.Context, payment *PaymentInfo) error
+	GetPayment(ctx context.Context, chargeID string) (*PaymentInfo, error)
+	GetUserPayments(ctx context.Context, userID int64) ([]PaymentInfo, error)
+}
+
+type SchedulerStorage interface {
+	// ScheduleJob saves the job with a new version, the job with the same id is replaced.
+	ScheduleJob(ctx context.Context, job *ScheduledJob) error
+	ClaimDueJobs(ctx context.Context, lease time.Duration, limit int) ([]ScheduledJob, error)
+	// CompleteJob removes the claimed job unless it was replaced or canceled after the claim.
+	CompleteJob(ctx context.Context, job *ScheduledJob) error
+	// RetryJob reschedules the claimed job unless it was replaced or canceled after the claim.
+	RetryJob(ctx context.Context, job *ScheduledJob) error
+	// ExtendJobLease keeps the claimed job hidden from other replicas while it runs.
+	ExtendJobLease(ctx context.Context, job *ScheduledJob, lease time.Duration) error
+	// CancelJob removes the job, ErrorJobNotFound is returned when there is no such job.
+	CancelJob(ctx context.Context, jobID string) error
+	GetJob(ctx context.Context, jobID string) (*ScheduledJob, error)
+}
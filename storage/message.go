@@ -98,11 +98,11 @@ func (s *RedisUserMessageStorage) SaveUserMessage(ctx context.Context, chatID in
 		return err
 	}
 
-	return s.client.SAdd(ctx, s.getMessagesKey(fmt.Sprint(chatID)), payloadBytes).Err()
+	return s.client.SAdd(ctx, s.getMessagesKey(chatKey(ctx, chatID)), payloadBytes).Err()
 }
 
 func (s *RedisUserMessageStorage) GetUserMessages(ctx context.Context, chatID int64) ([]MessageInfo, error) {
-	rawMessages, err := s.client.SMembers(ctx, s.getMessagesKey(fmt.Sprint(chatID))).Result()
+	rawMessages, err := s.client.SMembers(ctx, s.getMessagesKey(chatKey(ctx, chatID))).Result()
 
 	if err != nil {
 		return nil, err
@@ -124,7 +124,7 @@ func (s *RedisUserMessageStorage) GetUserMessages(ctx context.Context, chatID in
 }
 
 func (s *RedisUserMessageStorage) DeleteUserMessage(ctx context.Context, chatID int64) error {
-	return s.client.Del(ctx, s.getMessagesKey(fmt.Sprint(chatID))).Err()
+	return s.client.Del(ctx, s.getMessagesKey(chatKey(ctx, chatID))).Err()
 }
 
 func (s *RedisUserMessageStorage) SaveKeyboardInfo(ctx context.Context, chatID int64, messageID int, keyboard *KeyboardInfo) error {
@@ -202,8 +202,8 @@ func (i *InMemoryUserMessageStorage) DeleteCallbackMessage(_ context.Context, ca
 	return nil
 }
 
-func (i *InMemoryUserMessageStorage) SaveUserMessage(_ context.Context, chatID int64, messageID int, withKeyboard bool) error {
-	if ok := i.client.Set(i.getMessagesKey(fmt.Sprint(chatID)), &MessageInfo{
+func (i *InMemoryUserMessageStorage) SaveUserMessage(ctx context.Context, chatID int64, messageID int, withKeyboard bool) error {
+	if ok := i.client.Set(i.getMessagesKey(chatKey(ctx, chatID)), &MessageInfo{
 		MessageID:      messageID,
 		ChatID:         chatID,
 		InlineKeyboard: withKeyboard,
@@ -214,8 +214,8 @@ func (i *InMemoryUserMessageStorage) SaveUserMessage(_ context.Context, chatID i
 	return nil
 }
 
-func (i *InMemoryUserMessageStorage) GetUserMessages(_ context.Context, chatID int64) ([]MessageInfo, error) {
-	data, ok := i.client.Get(i.getMessagesKey(fmt.Sprint(chatID)))
+func (i *InMemoryUserMessageStorage) GetUserMessages(ctx context.Context, chatID int64) ([]MessageInfo, error) {
+	data, ok := i.client.Get(i.getMessagesKey(chatKey(ctx, chatID)))
 	if !ok {
 		return nil, domain.ErrorMessageNotFound
 	}
@@ -223,8 +223,8 @@ func (i *InMemoryUserMessageStorage) GetUserMessages(_ context.Context, chatID i
 	return data.([]MessageInfo), nil
 }
 
-func (i *InMemoryUserMessageStorage) DeleteUserMessage(_ context.Context, chatID int64) error {
-	i.client.Del(i.getMessagesKey(fmt.Sprint(chatID)))
+func (i *InMemoryUserMessageStorage) DeleteUserMessage(ctx context.Context, chatID int64) error {
+	i.client.Del(i.getMessagesKey(chatKey(ctx, chatID)))
 	return nil
 }
 
@@ -0,0 +1,427 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/redis/go-redis/v9"
+)
+
+type OutboxMessageType string
+
+const (
+	OutboxMessageSend OutboxMessageType = "send"
+	OutboxMessageEdit OutboxMessageType = "edit"
+)
+
+type OutboxMessage struct {
+	ID             string                       `json:"id"`
+	Type           OutboxMessageType            `json:"type"`
+	ChatID         int64                        `json:"chat_id"`
+	ThreadID       int                          `json:"thread_id,omitempty"`
+	MessageID      int                          `json:"message_id,omitempty"`
+	Text           string                       `json:"text"`
+	ParseMode      models.ParseMode             `json:"parse_mode,omitempty"`
+	Entities       []models.MessageEntity       `json:"entities,omitempty"`
+	InlineKeyboard *models.InlineKeyboardMarkup `json:"inline_keyboard,omitempty"`
+	ReplyKeyboard  *models.ReplyKeyboardMarkup  `json:"reply_keyboard,omitempty"`
+	RemoveKeyboard bool                         `json:"remove_keyboard,omitempty"`
+	Attempts       int                          `json:"attempts"`
+	NextAttemptAt  time.Time                    `json:"next_attempt_at"`
+	LastError      string                       `json:"last_error,omitempty"`
+	CreatedAt      time.Time                    `json:"created_at"`
+}
+
+// claimChatScript picks the first chat whose head message is due and leases it to the caller.
+var claimChatScript = redis.NewScript(`
+local chats = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #chats == 0 then
+	return false
+end
+redis.call('ZADD', KEYS[1], ARGV[2], chats[1])
+return chats[1]
+`)
+
+// completeMessageScript removes the head message of the chat and schedules the next one.
+var completeMessageScript = redis.NewScript(`
+if redis.call('LINDEX', KEYS[1], 0) == ARGV[1] then
+	redis.call('LPOP', KEYS[1])
+end
+redis.call('DEL', KEYS[2])
+if redis.call('LLEN', KEYS[1]) > 0 then
+	redis.call('ZADD', KEYS[3], ARGV[3], ARGV[2])
+else
+	redis.call('ZREM', KEYS[3], ARGV[2])
+end
+return true
+`)
+
+type RedisOutboxStorage struct {
+	botInstancePrefix string
+	client            *redis.Client
+	idempotencyTTL    time.Duration
+}
+
+func NewRedisOutboxStorage(
+	botInstancePrefix string,
+	client *redis.Client,
+	idempotencyTTL time.Duration,
+) *RedisOutboxStorage {
+	return &RedisOutboxStorage{botInstancePrefix: botInstancePrefix, client: client, idempotencyTTL: idempotencyTTL}
+}
+
+func (s *RedisOutboxStorage) getReadyChatsKey() string {
+	return fmt.Sprintf("%s:outbox:ready", s.botInstancePrefix)
+}
+
+func (s *RedisOutboxStorage) getChatQueueKey(chatID int64) string {
+	return fmt.Sprintf("%s:outbox:chat:%d", s.botInstancePrefix, chatID)
+}
+
+func (s *RedisOutboxStorage) getMessageKey(messageID string) string {
+	return fmt.Sprintf("%s:outbox:message:%s", s.botInstancePrefix, messageID)
+}
+
+func (s *RedisOutboxStorage) getIdempotencyKey(messageID string) string {
+	return fmt.Sprintf("%s:outbox:idempotency:%s", s.botInstancePrefix, messageID)
+}
+
+func (s *RedisOutboxStorage) getDeadLettersKey() string {
+	return fmt.Sprintf("%s:outbox:dead", s.botInstancePrefix)
+}
+
+func (s *RedisOutboxStorage) Enqueue(ctx context.Context, message *OutboxMessage) (bool, error) {
+	isNew, err := s.client.SetNX(ctx, s.getIdempotencyKey(message.ID), 1, s.idempotencyTTL).Result()
+
+	if err != nil || !isNew {
+		return false, err
+	}
+
+	payload, err := json.Marshal(message)
+
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.getMessageKey(message.ID), payload, 0)
+		pipe.RPush(ctx, s.getChatQueueKey(message.ChatID), message.ID)
+		pipe.ZAddNX(ctx, s.getReadyChatsKey(), redis.Z{
+			Score:  float64(message.NextAttemptAt.UnixMilli()),
+			Member: message.ChatID,
+		})
+		return nil
+	})
+
+	if err != nil {
+		s.client.Del(ctx, s.getIdempotencyKey(message.ID))
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *RedisOutboxStorage) ClaimChat(ctx context.Context, lease time.Duration) (int64, bool, error) {
+	now := time.Now()
+
+	chatID, err := claimChatScript.Run(ctx, s.client, []string{s.getReadyChatsKey()},
+		now.UnixMilli(), now.Add(lease).UnixMilli()).Text()
+
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	id, err := strconv.ParseInt(chatID, 10, 64)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+func (s *RedisOutboxStorage) ExtendChatLease(ctx context.Context, chatID int64, lease time.Duration) error {
+	// XX does not bring back the chat completed in the meantime
+	return s.client.ZAddXX(ctx, s.getReadyChatsKey(), redis.Z{
+		Score:  float64(time.Now().Add(lease).UnixMilli()),
+		Member: chatID,
+	}).Err()
+}
+
+func (s *RedisOutboxStorage) PeekMessage(ctx context.Context, chatID int64) (*OutboxMessage, error) {
+	messageID, err := s.client.LIndex(ctx, s.getChatQueueKey(chatID), 0).Result()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rawData, err := s.client.Get(ctx, s.getMessageKey(messageID)).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return &OutboxMessage{ID: messageID, ChatID: chatID}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var message OutboxMessage
+
+	if err = json.Unmarshal(rawData, &message); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (s *RedisOutboxStorage) CompleteMessage(ctx context.Context, message *OutboxMessage) error {
+	return completeMessageScript.Run(ctx, s.client,
+		[]string{s.getChatQueueKey(message.ChatID), s.getMessageKey(message.ID), s.getReadyChatsKey()},
+		message.ID, message.ChatID, time.Now().UnixMilli()).Err()
+}
+
+func (s *RedisOutboxStorage) RetryMessage(ctx context.Context, message *OutboxMessage) error {
+	payload, err := json.Marshal(message)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.getMessageKey(message.ID), payload, 0)
+		pipe.ZAdd(ctx, s.getReadyChatsKey(), redis.Z{
+			Score:  float64(message.NextAttemptAt.UnixMilli()),
+			Member: message.ChatID,
+		})
+		return nil
+	})
+
+	return err
+}
+
+func (s *RedisOutboxStorage) DeadLetter(ctx context.Context, message *OutboxMessage) error {
+	payload, err := json.Marshal(message)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.client.RPush(ctx, s.getDeadLettersKey(), payload).Err(); err != nil {
+		return err
+	}
+
+	return s.CompleteMessage(ctx, message)
+}
+
+func (s *RedisOutboxStorage) GetDeadLetters(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	rawMessages, err := s.client.LRange(ctx, s.getDeadLettersKey(), 0, int64(limit)-1).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]OutboxMessage, 0, len(rawMessages))
+
+	for _, rawMessage := range rawMessages {
+		var message OutboxMessage
+
+		if err := json.Unmarshal([]byte(rawMessage), &message); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+func (s *RedisOutboxStorage) DeleteDeadLetters(ctx context.Context) error {
+	return s.client.Del(ctx, s.getDeadLettersKey()).Err()
+}
+
+type InMemoryOutboxStorage struct {
+	mu             sync.Mutex
+	queues         map[int64][]*OutboxMessage
+	readyChats     map[int64]time.Time
+	idempotency    map[string]time.Time
+	deadLetters    []OutboxMessage
+	idempotencyTTL time.Duration
+}
+
+func NewInMemoryOutboxStorage(idempotencyTTL time.Duration) *InMemoryOutboxStorage {
+	return &InMemoryOutboxStorage{
+		queues:         make(map[int64][]*OutboxMessage),
+		readyChats:     make(map[int64]time.Time),
+		idempotency:    make(map[string]time.Time),
+		idempotencyTTL: idempotencyTTL,
+	}
+}
+
+func (i *InMemoryOutboxStorage) Enqueue(_ context.Context, message *OutboxMessage) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+
+	for key, expiresAt := range i.idempotency {
+		if now.After(expiresAt) {
+			delete(i.idempotency, key)
+		}
+	}
+
+	if _, ok := i.idempotency[message.ID]; ok {
+		return false, nil
+	}
+
+	i.idempotency[message.ID] = now.Add(i.idempotencyTTL)
+
+	stored := *message
+	i.queues[message.ChatID] = append(i.queues[message.ChatID], &stored)
+
+	if _, ok := i.readyChats[message.ChatID]; !ok {
+		i.readyChats[message.ChatID] = message.NextAttemptAt
+	}
+
+	return true, nil
+}
+
+func (i *InMemoryOutboxStorage) ClaimChat(_ context.Context, lease time.Duration) (int64, bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+
+	var chatID int64
+	var readyAt time.Time
+	found := false
+
+	for id, at := range i.readyChats {
+		if at.After(now) {
+			continue
+		}
+
+		if !found || at.Before(readyAt) {
+			chatID, readyAt, found = id, at, true
+		}
+	}
+
+	if !found {
+		return 0, false, nil
+	}
+
+	i.readyChats[chatID] = now.Add(lease)
+
+	return chatID, true, nil
+}
+
+func (i *InMemoryOutboxStorage) ExtendChatLease(_ context.Context, chatID int64, lease time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.readyChats[chatID]; ok {
+		i.readyChats[chatID] = time.Now().Add(lease)
+	}
+
+	return nil
+}
+
+func (i *InMemoryOutboxStorage) PeekMessage(_ context.Context, chatID int64) (*OutboxMessage, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	queue := i.queues[chatID]
+
+	if len(queue) == 0 {
+		return nil, nil
+	}
+
+	message := *queue[0]
+
+	return &message, nil
+}
+
+func (i *InMemoryOutboxStorage) CompleteMessage(_ context.Context, message *OutboxMessage) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.completeMessage(message)
+
+	return nil
+}
+
+func (i *InMemoryOutboxStorage) completeMessage(message *OutboxMessage) {
+	queue := i.queues[message.ChatID]
+
+	if len(queue) > 0 && queue[0].ID == message.ID {
+		queue = queue[1:]
+	}
+
+	if len(queue) == 0 {
+		delete(i.queues, message.ChatID)
+		delete(i.readyChats, message.ChatID)
+		return
+	}
+
+	i.queues[message.ChatID] = queue
+	i.readyChats[message.ChatID] = time.Now()
+}
+
+func (i *InMemoryOutboxStorage) RetryMessage(_ context.Context, message *OutboxMessage) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	queue := i.queues[message.ChatID]
+
+	if len(queue) > 0 && queue[0].ID == message.ID {
+		stored := *message
+		queue[0] = &stored
+	}
+
+	i.readyChats[message.ChatID] = message.NextAttemptAt
+
+	return nil
+}
+
+func (i *InMemoryOutboxStorage) DeadLetter(_ context.Context, message *OutboxMessage) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.deadLetters = append(i.deadLetters, *message)
+	i.completeMessage(message)
+
+	return nil
+}
+
+func (i *InMemoryOutboxStorage) GetDeadLetters(_ context.Context, limit int) ([]OutboxMessage, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if limit <= 0 || limit > len(i.deadLetters) {
+		limit = len(i.deadLetters)
+	}
+
+	return append([]OutboxMessage(nil), i.deadLetters[:limit]...), nil
+}
+
+func (i *InMemoryOutboxStorage) DeleteDeadLetters(_ context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.deadLetters = nil
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMemoryOutboxKeepsChatOrder(t *testing.T) {
+	outbox := NewInMemoryOutboxStorage(time.Hour)
+	ctx := t.Context()
+
+	for _, id := range []string{"1", "2", "1"} {
+		_, err := outbox.Enqueue(ctx, &OutboxMessage{ID: id, ChatID: 10, NextAttemptAt: time.Now()})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chatID, ok, _ := outbox.ClaimChat(ctx, time.Minute)
+
+	if !ok || chatID != 10 {
+		t.Fatal("chat 10 is not claimed")
+	}
+
+	if _, ok, _ = outbox.ClaimChat(ctx, time.Minute); ok {
+		t.Error("leased chat claimed twice")
+	}
+
+	message, _ := outbox.PeekMessage(ctx, chatID)
+
+	if message.ID != "1" {
+		t.Error("message.ID != 1")
+	}
+
+	_ = outbox.DeadLetter(ctx, message)
+
+	if _, ok, _ = outbox.ClaimChat(ctx, time.Minute); !ok {
+		t.Error("chat is not released after completion")
+	}
+
+	message, _ = outbox.PeekMessage(ctx, chatID)
+
+	if message.ID != "2" {
+		t.Error("duplicate message was not ignored")
+	}
+
+	_ = outbox.CompleteMessage(ctx, message)
+
+	if _, ok, _ = outbox.ClaimChat(ctx, time.Minute); ok {
+		t.Error("empty chat is claimed")
+	}
+
+	deadLetters, _ := outbox.GetDeadLetters(ctx, 10)
+
+	if len(deadLetters) != 1 || deadLetters[0].ID != "1" {
+		t.Error("dead letter is not recorded")
+	}
+}
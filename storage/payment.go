@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPaid     PaymentStatus = "paid"
+	PaymentStatusRefunded PaymentStatus = "refunded"
+)
+
+type PaymentInfo struct {
+	// ChargeID is the telegram payment charge id, it is required to refund Stars payments.
+	ChargeID         string        `json:"charge_id"`
+	ProviderChargeID string        `json:"provider_charge_id,omitempty"`
+	UserID           int64         `json:"user_id"`
+	ChatID           int64         `json:"chat_id"`
+	Currency         string        `json:"currency"`
+	TotalAmount      int           `json:"total_amount"`
+	Payload          string        `json:"payload"`
+	ShippingOptionID string        `json:"shipping_option_id,omitempty"`
+	IsRecurring      bool          `json:"is_recurring,omitempty"`
+	ExpiresAt        time.Time     `json:"expires_at,omitempty"`
+	Status           PaymentStatus `json:"status"`
+	CreatedAt        time.Time     `json:"created_at"`
+	RefundedAt       time.Time     `json:"refunded_at,omitempty"`
+}
+
+type RedisPaymentStorage struct {
+	botInstancePrefix string
+	client            *redis.Client
+}
+
+func NewRedisPaymentStorage(
+	botInstancePrefix string,
+	client *redis.Client,
+) *RedisPaymentStorage {
+	return &RedisPaymentStorage{botInstancePrefix: botInstancePrefix, client: client}
+}
+
+func (s *RedisPaymentStorage) getPaymentsKey() string {
+	return fmt.Sprintf("%s:payments", s.botInstancePrefix)
+}
+
+func (s *RedisPaymentStorage) getUserPaymentsKey(userID int64) string {
+	return fmt.Sprintf("%s:payments:user:%d", s.botInstancePrefix, userID)
+}
+
+func (s *RedisPaymentStorage) SavePayment(ctx context.Context, payment *PaymentInfo) error {
+	payload, err := json.Marshal(payment)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.getPaymentsKey(), payment.ChargeID, payload)
+		pipe.ZAdd(ctx, s.getUserPaymentsKey(payment.UserID), redis.Z{
+			Score:  float64(payment.CreatedAt.Unix()),
+			Member: payment.ChargeID,
+		})
+
+		return nil
+	})
+
+	return err
+}
+
+func (s *RedisPaymentStorage) GetPayment(ctx context.Context, chargeID string) (*PaymentInfo, error) {
+	rawData, err := s.client.HGet(ctx, s.getPaymentsKey(), chargeID).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrorPaymentNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var payment PaymentInfo
+
+	if err = json.Unmarshal(rawData, &payment); err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+func (s *RedisPaymentStorage) GetUserPayments(ctx context.Context, userID int64) ([]PaymentInfo, error) {
+	chargeIDs, err := s.client.ZRange(ctx, s.getUserPaymentsKey(userID), 0, -1).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chargeIDs) == 0 {
+		return nil, nil
+	}
+
+	rawPayments, err := s.client.HMGet(ctx, s.getPaymentsKey(), chargeIDs...).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	payments := make([]PaymentInfo, 0, len(rawPayments))
+
+	for _, rawPayment := range rawPayments {
+		rawData, ok := rawPayment.(string)
+
+		if !ok {
+			continue
+		}
+
+		var payment PaymentInfo
+
+		if err = json.Unmarshal([]byte(rawData), &payment); err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+type InMemoryPaymentStorage struct {
+	mu       sync.RWMutex
+	payments map[string]PaymentInfo
+}
+
+func NewInMemoryPaymentStorage() *InMemoryPaymentStorage {
+	return &InMemoryPaymentStorage{payments: make(map[string]PaymentInfo)}
+}
+
+func (i *InMemoryPaymentStorage) SavePayment(_ context.Context, payment *PaymentInfo) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.payments[payment.ChargeID] = *payment
+
+	return nil
+}
+
+func (i *InMemoryPaymentStorage) GetPayment(_ context.Context, chargeID string) (*PaymentInfo, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	payment, ok := i.payments[chargeID]
+
+	if !ok {
+		return nil, domain.ErrorPaymentNotFound
+	}
+
+	return &payment, nil
+}
+
+func (i *InMemoryPaymentStorage) GetUserPayments(_ context.Context, userID int64) ([]PaymentInfo, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var payments []PaymentInfo
+
+	for _, payment := range i.payments {
+		if payment.UserID == userID {
+			payments = append(payments, payment)
+		}
+	}
+
+	sort.Slice(payments, func(a, b int) bool {
+		return payments[a].CreatedAt.Before(payments[b].CreatedAt)
+	})
+
+	return payments, nil
+}
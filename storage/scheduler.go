@@ -0,0 +1,350 @@
+package storage
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type ScheduledJobType string
+
+const (
+	ScheduledJobSend    ScheduledJobType = "send"
+	ScheduledJobEdit    ScheduledJobType = "edit"
+	ScheduledJobDelete  ScheduledJobType = "delete"
+	ScheduledJobHandler ScheduledJobType = "handler"
+)
+
+type ScheduledJob struct {
+	ID             string                       `json:"id"`
+	Type           ScheduledJobType             `json:"type"`
+	ChatID         int64                        `json:"chat_id"`
+	ThreadID       int                          `json:"thread_id,omitempty"`
+	MessageID      int                          `json:"message_id,omitempty"`
+	Text           string                       `json:"text,omitempty"`
+	ParseMode      models.ParseMode             `json:"parse_mode,omitempty"`
+	Entities       []models.MessageEntity       `json:"entities,omitempty"`
+	InlineKeyboard *models.InlineKeyboardMarkup `json:"inline_keyboard,omitempty"`
+	HandlerName    string                       `json:"handler_name,omitempty"`
+	Payload        string                       `json:"payload,omitempty"`
+	RunAt          time.Time                    `json:"run_at"`
+	Attempts       int                          `json:"attempts"`
+	LastError      string                       `json:"last_error,omitempty"`
+	CreatedAt      time.Time                    `json:"created_at"`
+	// Version changes on every save, so the replica running the job notices it was replaced or canceled.
+	Version string `json:"version"`
+}
+
+// claimJobsScript picks due jobs and leases them to the caller by moving their score to the lease end.
+var claimJobsScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+local jobs = {}
+for _, id in ipairs(ids) do
+	local job = redis.call('HGET', KEYS[2], id)
+	if job then
+		redis.call('ZADD', KEYS[1], ARGV[2], id)
+		table.insert(jobs, job)
+	else
+		redis.call('ZREM', KEYS[1], id)
+	end
+end
+return jobs
+`)
+
+// claimedJobScript checks that the job was not replaced or canceled since it was claimed.
+const claimedJobScript = `
+local job = redis.call('HGET', KEYS[2], ARGV[1])
+if not job or (cjson.decode(job).version or '') ~= ARGV[2] then
+	return 0
+end
+`
+
+// completeJobScript removes the claimed job.
+var completeJobScript = redis.NewScript(claimedJobScript + `
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+return 1
+`)
+
+// retryJobScript saves the claimed job with the new run time.
+var retryJobScript = redis.NewScript(claimedJobScript + `
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+redis.call('ZADD', KEYS[1], ARGV[4], ARGV[1])
+return 1
+`)
+
+// extendJobLeaseScript moves the lease end of the claimed job.
+var extendJobLeaseScript = redis.NewScript(claimedJobScript + `
+redis.call('ZADD', KEYS[1], 'XX', ARGV[3], ARGV[1])
+return 1
+`)
+
+type RedisSchedulerStorage struct {
+	botInstancePrefix string
+	client            *redis.Client
+}
+
+func NewRedisSchedulerStorage(
+	botInstancePrefix string,
+	client *redis.Client,
+) *RedisSchedulerStorage {
+	return &RedisSchedulerStorage{botInstancePrefix: botInstancePrefix, client: client}
+}
+
+func (s *RedisSchedulerStorage) getScheduleKey() string {
+	return fmt.Sprintf("%s:scheduler:schedule", s.botInstancePrefix)
+}
+
+func (s *RedisSchedulerStorage) getJobsKey() string {
+	return fmt.Sprintf("%s:scheduler:jobs", s.botInstancePrefix)
+}
+
+func (s *RedisSchedulerStorage) ScheduleJob(ctx context.Context, job *ScheduledJob) error {
+	job.Version = newJobVersion()
+
+	payload, err := json.Marshal(job)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.getJobsKey(), job.ID, payload)
+		pipe.ZAdd(ctx, s.getScheduleKey(), redis.Z{
+			Score:  float64(job.RunAt.UnixMilli()),
+			Member: job.ID,
+		})
+		return nil
+	})
+
+	return err
+}
+
+func (s *RedisSchedulerStorage) ClaimDueJobs(ctx context.Context, lease time.Duration, limit int) ([]ScheduledJob, error) {
+	now := time.Now()
+
+	rawJobs, err := claimJobsScript.Run(ctx, s.client, []string{s.getScheduleKey(), s.getJobsKey()},
+		now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]ScheduledJob, 0, len(rawJobs))
+
+	for _, rawJob := range rawJobs {
+		var job ScheduledJob
+
+		if err = json.Unmarshal([]byte(rawJob), &job); err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+func (s *RedisSchedulerStorage) CompleteJob(ctx context.Context, job *ScheduledJob) error {
+	return completeJobScript.Run(ctx, s.client, []string{s.getScheduleKey(), s.getJobsKey()}, job.ID, job.Version).Err()
+}
+
+func (s *RedisSchedulerStorage) RetryJob(ctx context.Context, job *ScheduledJob) error {
+	claimedVersion := job.Version
+	job.Version = newJobVersion()
+
+	payload, err := json.Marshal(job)
+
+	if err != nil {
+		return err
+	}
+
+	return retryJobScript.Run(ctx, s.client, []string{s.getScheduleKey(), s.getJobsKey()},
+		job.ID, claimedVersion, payload, job.RunAt.UnixMilli()).Err()
+}
+
+func (s *RedisSchedulerStorage) ExtendJobLease(ctx context.Context, job *ScheduledJob, lease time.Duration) error {
+	return extendJobLeaseScript.Run(ctx, s.client, []string{s.getScheduleKey(), s.getJobsKey()},
+		job.ID, job.Version, time.Now().Add(lease).UnixMilli()).Err()
+}
+
+func (s *RedisSchedulerStorage) CancelJob(ctx context.Context, jobID string) error {
+	var deleted *redis.IntCmd
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.getScheduleKey(), jobID)
+		deleted = pipe.HDel(ctx, s.getJobsKey(), jobID)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if deleted.Val() == 0 {
+		return domain.ErrorJobNotFound
+	}
+
+	return nil
+}
+
+func (s *RedisSchedulerStorage) GetJob(ctx context.Context, jobID string) (*ScheduledJob, error) {
+	rawData, err := s.client.HGet(ctx, s.getJobsKey(), jobID).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrorJobNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var job ScheduledJob
+
+	if err = json.Unmarshal(rawData, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+type InMemorySchedulerStorage struct {
+	mu          sync.Mutex
+	jobs        map[string]ScheduledJob
+	leasedUntil map[string]time.Time
+}
+
+func NewInMemorySchedulerStorage() *InMemorySchedulerStorage {
+	return &InMemorySchedulerStorage{
+		jobs:        make(map[string]ScheduledJob),
+		leasedUntil: make(map[string]time.Time),
+	}
+}
+
+func (i *InMemorySchedulerStorage) ScheduleJob(_ context.Context, job *ScheduledJob) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	job.Version = newJobVersion()
+	i.jobs[job.ID] = *job
+	delete(i.leasedUntil, job.ID)
+
+	return nil
+}
+
+func (i *InMemorySchedulerStorage) ClaimDueJobs(_ context.Context, lease time.Duration, limit int) ([]ScheduledJob, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	jobs := make([]ScheduledJob, 0)
+
+	for id, job := range i.jobs {
+		if job.RunAt.After(now) || i.leasedUntil[id].After(now) {
+			continue
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].RunAt.Before(jobs[b].RunAt)
+	})
+
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+
+	for _, job := range jobs {
+		i.leasedUntil[job.ID] = now.Add(lease)
+	}
+
+	return jobs, nil
+}
+
+func (i *InMemorySchedulerStorage) CompleteJob(_ context.Context, job *ScheduledJob) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.isClaimed(job) {
+		delete(i.jobs, job.ID)
+		delete(i.leasedUntil, job.ID)
+	}
+
+	return nil
+}
+
+func (i *InMemorySchedulerStorage) RetryJob(_ context.Context, job *ScheduledJob) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.isClaimed(job) {
+		job.Version = newJobVersion()
+		i.jobs[job.ID] = *job
+		delete(i.leasedUntil, job.ID)
+	}
+
+	return nil
+}
+
+func (i *InMemorySchedulerStorage) ExtendJobLease(_ context.Context, job *ScheduledJob, lease time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.isClaimed(job) {
+		i.leasedUntil[job.ID] = time.Now().Add(lease)
+	}
+
+	return nil
+}
+
+func (i *InMemorySchedulerStorage) CancelJob(_ context.Context, jobID string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.jobs[jobID]; !ok {
+		return domain.ErrorJobNotFound
+	}
+
+	delete(i.jobs, jobID)
+	delete(i.leasedUntil, jobID)
+
+	return nil
+}
+
+// isClaimed reports whether the stored job is still the claimed version.
+func (i *InMemorySchedulerStorage) isClaimed(job *ScheduledJob) bool {
+	stored, ok := i.jobs[job.ID]
+
+	return ok && stored.Version == job.Version
+}
+
+func (i *InMemorySchedulerStorage) GetJob(_ context.Context, jobID string) (*ScheduledJob, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	job, ok := i.jobs[jobID]
+
+	if !ok {
+		return nil, domain.ErrorJobNotFound
+	}
+
+	return &job, nil
+}
+
+func newJobVersion() string {
+	buf := make([]byte, 8)
+	_, _ = cryptorand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
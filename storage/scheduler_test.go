@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+)
+
+func TestInMemorySchedulerLeasesDueJobs(t *testing.T) {
+	scheduler := NewInMemorySchedulerStorage()
+	ctx := t.Context()
+
+	_ = scheduler.ScheduleJob(ctx, &ScheduledJob{ID: "late", RunAt: time.Now().Add(-time.Second)})
+	_ = scheduler.ScheduleJob(ctx, &ScheduledJob{ID: "early", RunAt: time.Now().Add(-time.Minute)})
+	_ = scheduler.ScheduleJob(ctx, &ScheduledJob{ID: "future", RunAt: time.Now().Add(time.Hour)})
+
+	claimed, _ := scheduler.ClaimDueJobs(ctx, time.Minute, 10)
+	jobs := claimed
+
+	if len(jobs) != 2 || jobs[0].ID != "early" || jobs[1].ID != "late" {
+		t.Fatalf("unexpected due jobs %v", jobs)
+	}
+
+	if jobs, _ = scheduler.ClaimDueJobs(ctx, time.Minute, 10); len(jobs) != 0 {
+		t.Error("leased jobs claimed twice")
+	}
+
+	_ = scheduler.CompleteJob(ctx, &claimed[0])
+
+	if _, err := scheduler.GetJob(ctx, "early"); !errors.Is(err, domain.ErrorJobNotFound) {
+		t.Error("completed job is not removed")
+	}
+
+	_ = scheduler.ScheduleJob(ctx, &ScheduledJob{ID: "late", RunAt: time.Now(), Attempts: 1})
+
+	if jobs, _ = scheduler.ClaimDueJobs(ctx, time.Minute, 10); len(jobs) != 1 || jobs[0].Attempts != 1 {
+		t.Error("rescheduled job is not released")
+	}
+}
+
+func TestInMemorySchedulerKeepsReplacedJob(t *testing.T) {
+	scheduler := NewInMemorySchedulerStorage()
+	ctx := t.Context()
+
+	_ = scheduler.ScheduleJob(ctx, &ScheduledJob{ID: "job", RunAt: time.Now()})
+
+	claimed, _ := scheduler.ClaimDueJobs(ctx, time.Minute, 10)
+
+	_ = scheduler.ScheduleJob(ctx, &ScheduledJob{ID: "job", RunAt: time.Now().Add(time.Hour), Text: "replaced"})
+	_ = scheduler.CompleteJob(ctx, &claimed[0])
+
+	if job, err := scheduler.GetJob(ctx, "job"); err != nil || job.Text != "replaced" {
+		t.Fatalf("expected replaced job to stay, got %v, %v", job, err)
+	}
+
+	_ = scheduler.CancelJob(ctx, "job")
+
+	claimed[0].RunAt = time.Now()
+	_ = scheduler.RetryJob(ctx, &claimed[0])
+
+	if _, err := scheduler.GetJob(ctx, "job"); !errors.Is(err, domain.ErrorJobNotFound) {
+		t.Fatalf("expected canceled job not to be retried, got %v", err)
+	}
+
+	if err := scheduler.CancelJob(ctx, "job"); !errors.Is(err, domain.ErrorJobNotFound) {
+		t.Fatalf("expected not found on second cancel, got %v", err)
+	}
+}
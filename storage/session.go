@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/ristretto"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type RedisSessionStorage struct {
+	botInstancePrefix string
+	client            *redis.Client
+	ttl               time.Duration
+}
+
+// NewRedisSessionStorage creates the storage of sessions expiring after ttl since the last save, zero ttl keeps them
+// until the action of the user returns to 0.
+func NewRedisSessionStorage(
+	botInstancePrefix string,
+	client *redis.Client,
+	ttl time.Duration,
+) *RedisSessionStorage {
+	return &RedisSessionStorage{botInstancePrefix: botInstancePrefix, client: client, ttl: ttl}
+}
+
+func (s *RedisSessionStorage) getSessionKey(ctx context.Context, userID int64) string {
+	return fmt.Sprintf("%s:user:session:%s", s.botInstancePrefix, chatKey(ctx, userID))
+}
+
+func (s *RedisSessionStorage) SaveSession(ctx context.Context, userID int64, data []byte) error {
+	return s.client.Set(ctx, s.getSessionKey(ctx, userID), data, s.ttl).Err()
+}
+
+func (s *RedisSessionStorage) GetSession(ctx context.Context, userID int64) ([]byte, error) {
+	data, err := s.client.Get(ctx, s.getSessionKey(ctx, userID)).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrorSessionNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *RedisSessionStorage) DeleteSession(ctx context.Context, userID int64) error {
+	return s.client.Del(ctx, s.getSessionKey(ctx, userID)).Err()
+}
+
+type InMemorySessionStorage struct {
+	client *ristretto.Cache
+	ttl    time.Duration
+}
+
+func NewInMemorySessionStorage(client *ristretto.Cache, ttl time.Duration) *InMemorySessionStorage {
+	return &InMemorySessionStorage{client: client, ttl: ttl}
+}
+
+func (i *InMemorySessionStorage) getSessionKey(ctx context.Context, userID int64) string {
+	return fmt.Sprintf("user:session:%s", chatKey(ctx, userID))
+}
+
+func (i *InMemorySessionStorage) SaveSession(ctx context.Context, userID int64, data []byte) error {
+	if ok := i.client.SetWithTTL(i.getSessionKey(ctx, userID), data, 0, i.ttl); !ok {
+		return errors.New("failed to save session")
+	}
+
+	// the next step of the flow has to read the session, so wait for the buffered write
+	i.client.Wait()
+
+	return nil
+}
+
+func (i *InMemorySessionStorage) GetSession(ctx context.Context, userID int64) ([]byte, error) {
+	data, ok := i.client.Get(i.getSessionKey(ctx, userID))
+
+	if !ok {
+		return nil, domain.ErrorSessionNotFound
+	}
+
+	return data.([]byte), nil
+}
+
+func (i *InMemorySessionStorage) DeleteSession(ctx context.Context, userID int64) error {
+	i.client.Del(i.getSessionKey(ctx, userID))
+	return nil
+}
+
+// Session is the typed session of the user, the value is stored as JSON.
+type Session[T any] struct {
+	storage SessionStorage
+	userID  int64
+}
+
+func NewSession[T any](storage SessionStorage, userID int64) *Session[T] {
+	return &Session[T]{storage: storage, userID: userID}
+}
+
+// Get returns the zero value when the user has no session.
+func (s *Session[T]) Get(ctx context.Context) (T, error) {
+	var value T
+
+	data, err := s.storage.GetSession(ctx, s.userID)
+
+	if errors.Is(err, domain.ErrorSessionNotFound) {
+		return value, nil
+	}
+
+	if err != nil {
+		return value, err
+	}
+
+	if err = json.Unmarshal(data, &value); err != nil {
+		return value, err
+	}
+
+	return value, nil
+}
+
+func (s *Session[T]) Save(ctx context.Context, value T) error {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	return s.storage.SaveSession(ctx, s.userID, data)
+}
+
+// Update saves the session changed by the function, the session is not saved when the function fails.
+func (s *Session[T]) Update(ctx context.Context, update func(value *T) error) error {
+	value, err := s.Get(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if err = update(&value); err != nil {
+		return err
+	}
+
+	return s.Save(ctx, value)
+}
+
+func (s *Session[T]) Clear(ctx context.Context) error {
+	return s.storage.DeleteSession(ctx, s.userID)
+}
+
+type sessionCtxKey struct{}
+
+type sessionScope struct {
+	storage SessionStorage
+	userID  int64
+}
+
+// WithSession makes the session of the user available to handlers called with the returned context.
+func WithSession(ctx context.Context, storage SessionStorage, userID int64) context.Context {
+	return context.WithValue(ctx, sessionCtxKey{}, sessionScope{storage: storage, userID: userID})
+}
+
+type actionStorageCtxKey struct{}
+
+// WithActionStorage makes the action storage of the bot available to handlers called with the returned context.
+func WithActionStorage(ctx context.Context, actions UserActionStorage) context.Context {
+	return context.WithValue(ctx, actionStorageCtxKey{}, actions)
+}
+
+// ActionStorageFromContext returns the action storage of the bot which received the handled update, it is nil
+// outside of handlers.
+func ActionStorageFromContext(ctx context.Context) UserActionStorage {
+	actions, _ := ctx.Value(actionStorageCtxKey{}).(UserActionStorage)
+
+	return actions
+}
+
+// SessionFromContext returns the typed session of the user of the handled update.
+func SessionFromContext[T any](ctx context.Context) (*Session[T], error) {
+	scope, ok := ctx.Value(sessionCtxKey{}).(sessionScope)
+
+	if !ok {
+		return nil, domain.ErrorSessionNotConfigured
+	}
+
+	return NewSession[T](scope.storage, scope.userID), nil
+}
+
+// SessionActionStorage clears the session of the user when the action returns to 0. Only the actions saved through
+// this storage clear sessions, handlers get it with ActionStorageFromContext.
+type SessionActionStorage struct {
+	UserActionStorage
+	sessions SessionStorage
+}
+
+func NewSessionActionStorage(actions UserActionStorage, sessions SessionStorage) *SessionActionStorage {
+	return &SessionActionStorage{UserActionStorage: actions, sessions: sessions}
+}
+
+func (s *SessionActionStorage) SaveAction(ctx context.Context, userID int64, action int) error {
+	if err := s.UserActionStorage.SaveAction(ctx, userID, action); err != nil {
+		return err
+	}
+
+	if action != 0 {
+		return nil
+	}
+
+	return s.sessions.DeleteSession(ctx, userID)
+}
+
+// CompareAndSaveAction is atomic only when the wrapped storage implements ActionComparer.
+func (s *SessionActionStorage) CompareAndSaveAction(ctx context.Context, userID int64, expected, action int) (bool, error) {
+	saved, err := CompareAndSaveAction(ctx, s.UserActionStorage, userID, expected, action)
+
+	if err != nil || !saved || action != 0 {
+		return saved, err
+	}
+
+	return true, s.sessions.DeleteSession(ctx, userID)
+}
+
+// SaveActionWithRollback clears the session only when the change is committed, so the rolled back flow keeps it.
+func (s *SessionActionStorage) SaveActionWithRollback(ctx context.Context, userID int64, action int) (func(err error) error, error) {
+	rollback, err := s.UserActionStorage.SaveActionWithRollback(ctx, userID, action)
+
+	if err != nil || action != 0 {
+		return rollback, err
+	}
+
+	return func(err error) error {
+		if err != nil {
+			return rollback(err)
+		}
+
+		return s.sessions.DeleteSession(ctx, userID)
+	}, nil
+}
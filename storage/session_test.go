@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/nejkit/telegram-bot-core/v2/domain"
+)
+
+type transferSession struct {
+	Name   string
+	Amount int
+}
+
+func newTestCache(t *testing.T) *ristretto.Cache {
+	cache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 1000, BufferItems: 64})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cache
+}
+
+func TestSessionFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := SessionFromContext[transferSession](ctx); !errors.Is(err, domain.ErrorSessionNotConfigured) {
+		t.Fatalf("expected session not configured, got %v", err)
+	}
+
+	ctx = WithSession(ctx, NewInMemorySessionStorage(newTestCache(t), 0), 1)
+
+	session, err := SessionFromContext[transferSession](ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := session.Get(ctx); err != nil || value != (transferSession{}) {
+		t.Fatalf("expected empty session, got %v, %v", value, err)
+	}
+
+	_ = session.Save(ctx, transferSession{Name: "alice"})
+	_ = session.Update(ctx, func(value *transferSession) error {
+		value.Amount = 10
+		return nil
+	})
+
+	if value, _ := session.Get(ctx); value != (transferSession{Name: "alice", Amount: 10}) {
+		t.Fatalf("expected updated session, got %v", value)
+	}
+}
+
+func TestSessionActionStorageClearsSession(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestCache(t)
+	sessions := NewInMemorySessionStorage(cache, 0)
+	actions := NewSessionActionStorage(NewInMemoryUserActionStorage[int](cache), sessions)
+	session := NewSession[transferSession](sessions, 1)
+
+	_ = actions.SaveAction(ctx, 1, 2)
+	_ = session.Save(ctx, transferSession{Name: "alice"})
+	_ = actions.SaveAction(ctx, 1, 3)
+
+	if value, _ := session.Get(ctx); value.Name != "alice" {
+		t.Fatal("expected session to be kept while the flow continues")
+	}
+
+	_ = actions.SaveAction(ctx, 1, 0)
+
+	if _, err := sessions.GetSession(ctx, 1); !errors.Is(err, domain.ErrorSessionNotFound) {
+		t.Fatalf("expected session to be cleared, got %v", err)
+	}
+}
+
+func TestSessionActionStorageRollback(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestCache(t)
+	sessions := NewInMemorySessionStorage(cache, 0)
+	actions := NewSessionActionStorage(NewInMemoryUserActionStorage[int](cache), sessions)
+	session := NewSession[transferSession](sessions, 1)
+
+	_ = actions.SaveAction(ctx, 1, 2)
+	_ = session.Save(ctx, transferSession{Name: "alice"})
+
+	rollback, _ := actions.SaveActionWithRollback(ctx, 1, 0)
+	_ = rollback(errors.New("handler failed"))
+
+	if value, _ := session.Get(ctx); value.Name != "alice" {
+		t.Fatal("expected session to be kept when the flow is rolled back")
+	}
+
+	if action, _ := actions.GetAction(ctx, 1); action != 2 {
+		t.Fatalf("expected action rolled back, got %d", action)
+	}
+
+	rollback, _ = actions.SaveActionWithRollback(ctx, 1, 0)
+	_ = rollback(nil)
+
+	if _, err := sessions.GetSession(ctx, 1); !errors.Is(err, domain.ErrorSessionNotFound) {
+		t.Fatalf("expected session cleared after commit, got %v", err)
+	}
+}
+
+func TestSessionActionStorageCompareAndSave(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestCache(t)
+	sessions := NewInMemorySessionStorage(cache, 0)
+	actions := NewSessionActionStorage(NewInMemoryUserActionStorage[int](cache), sessions)
+	session := NewSession[transferSession](sessions, 1)
+
+	_ = actions.SaveAction(ctx, 1, 3)
+	_ = session.Save(ctx, transferSession{Name: "alice"})
+
+	if saved, _ := actions.CompareAndSaveAction(ctx, 1, 2, 0); saved {
+		t.Fatal("expected action not saved when the user is in another action")
+	}
+
+	if value, _ := session.Get(ctx); value.Name != "alice" {
+		t.Fatal("expected session to be kept")
+	}
+
+	if saved, _ := actions.CompareAndSaveAction(ctx, 1, 3, 0); !saved {
+		t.Fatal("expected action saved when the user is in the expected action")
+	}
+
+	if _, err := sessions.GetSession(ctx, 1); !errors.Is(err, domain.ErrorSessionNotFound) {
+		t.Fatalf("expected session cleared with the action, got %v", err)
+	}
+}
+
+// plainActionStorage hides CompareAndSaveAction of the wrapped storage.
+type plainActionStorage struct {
+	UserActionStorage
+}
+
+func TestCompareAndSaveActionFallback(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestCache(t)
+	sessions := NewInMemorySessionStorage(cache, 0)
+	actions := NewSessionActionStorage(plainActionStorage{NewInMemoryUserActionStorage[int](cache)}, sessions)
+
+	_ = actions.SaveAction(ctx, 1, 3)
+	_ = sessions.SaveSession(ctx, 1, []byte(`{}`))
+
+	if saved, _ := CompareAndSaveAction(ctx, actions, 1, 2, 0); saved {
+		t.Fatal("expected action not saved when the user is in another action")
+	}
+
+	if saved, _ := CompareAndSaveAction(ctx, actions, 1, 3, 0); !saved {
+		t.Fatal("expected action saved when the user is in the expected action")
+	}
+
+	if _, err := sessions.GetSession(ctx, 1); !errors.Is(err, domain.ErrorSessionNotFound) {
+		t.Fatalf("expected session cleared with the action, got %v", err)
+	}
+}
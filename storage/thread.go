@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+type threadCtxKey struct{}
+
+// WithThread scopes user actions and stored messages of calls done with the returned context to the forum topic.
+func WithThread(ctx context.Context, threadID int) context.Context {
+	return context.WithValue(ctx, threadCtxKey{}, threadID)
+}
+
+func ThreadFromContext(ctx context.Context) int {
+	threadID, _ := ctx.Value(threadCtxKey{}).(int)
+
+	return threadID
+}
+
+// chatKey returns the storage key part of the chat, it includes the thread of the context.
+func chatKey(ctx context.Context, chatID int64) string {
+	if threadID := ThreadFromContext(ctx); threadID != 0 {
+		return fmt.Sprintf("%d:thread:%d", chatID, threadID)
+	}
+
+	return fmt.Sprint(chatID)
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChatKey(t *testing.T) {
+	ctx := context.Background()
+
+	if key := chatKey(ctx, -100); key != "-100" {
+		t.Fatalf("expected chat key without thread, got %s", key)
+	}
+
+	if key := chatKey(WithThread(ctx, 7), -100); key != "-100:thread:7" {
+		t.Fatalf("expected chat key with thread, got %s", key)
+	}
+}
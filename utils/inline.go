@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"strconv"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// MaxInlineResults is the maximum amount of results telegram accepts in one answer.
+const MaxInlineResults = 50
+
+func NewInlineArticle(id, title, text string) *models.InlineQueryResultArticle {
+	return &models.InlineQueryResultArticle{
+		ID:                  id,
+		Title:               title,
+		InputMessageContent: &models.InputTextMessageContent{MessageText: text},
+	}
+}
+
+// FormattedText is the text together with its parse mode and entities, e.g. client.TextBuilder.
+type FormattedText interface {
+	String() string
+	ParseMode() models.ParseMode
+	Entities() []models.MessageEntity
+}
+
+// NewInlineFormattedArticle creates the article sending the formatted text.
+func NewInlineFormattedArticle(id, title string, text FormattedText) *models.InlineQueryResultArticle {
+	return &models.InlineQueryResultArticle{
+		ID:    id,
+		Title: title,
+		InputMessageContent: &models.InputTextMessageContent{
+			MessageText: text.String(),
+			ParseMode:   text.ParseMode(),
+			Entities:    text.Entities(),
+		},
+	}
+}
+
+func NewInlinePhoto(id, photoURL, thumbnailURL string) *models.InlineQueryResultPhoto {
+	return &models.InlineQueryResultPhoto{
+		ID:           id,
+		PhotoURL:     photoURL,
+		ThumbnailURL: thumbnailURL,
+	}
+}
+
+func NewInlineDocument(id, title, documentURL, mimeType string) *models.InlineQueryResultDocument {
+	return &models.InlineQueryResultDocument{
+		ID:          id,
+		Title:       title,
+		DocumentURL: documentURL,
+		MimeType:    mimeType,
+	}
+}
+
+func NewInlineCachedPhoto(id, fileID string) *models.InlineQueryResultCachedPhoto {
+	return &models.InlineQueryResultCachedPhoto{
+		ID:          id,
+		PhotoFileID: fileID,
+	}
+}
+
+func NewInlineCachedDocument(id, title, fileID string) *models.InlineQueryResultCachedDocument {
+	return &models.InlineQueryResultCachedDocument{
+		ID:             id,
+		Title:          title,
+		DocumentFileID: fileID,
+	}
+}
+
+func NewInlineCachedVideo(id, title, fileID string) *models.InlineQueryResultCachedVideo {
+	return &models.InlineQueryResultCachedVideo{
+		ID:          id,
+		Title:       title,
+		VideoFileID: fileID,
+	}
+}
+
+func NewInlineCachedAudio(id, fileID string) *models.InlineQueryResultCachedAudio {
+	return &models.InlineQueryResultCachedAudio{
+		ID:          id,
+		AudioFileID: fileID,
+	}
+}
+
+func NewInlineCachedVoice(id, title, fileID string) *models.InlineQueryResultCachedVoice {
+	return &models.InlineQueryResultCachedVoice{
+		ID:          id,
+		Title:       title,
+		VoiceFileID: fileID,
+	}
+}
+
+func NewInlineCachedAnimation(id, fileID string) *models.InlineQueryResultCachedMpeg4Gif {
+	return &models.InlineQueryResultCachedMpeg4Gif{
+		ID:          id,
+		Mpeg4FileID: fileID,
+	}
+}
+
+func NewInlineCachedSticker(id, fileID string) *models.InlineQueryResultCachedSticker {
+	return &models.InlineQueryResultCachedSticker{
+		ID:            id,
+		StickerFileID: fileID,
+	}
+}
+
+// InlineOffset parses the offset of the inline query built by NextInlineOffset, invalid offsets start from the beginning.
+func InlineOffset(offset string) int {
+	position, err := strconv.Atoi(offset)
+
+	if err != nil || position < 0 {
+		return 0
+	}
+
+	return position
+}
+
+// NextInlineOffset returns the offset of the next page, empty offset tells telegram there are no more results.
+func NextInlineOffset(position, pageSize, pageCount int) string {
+	if pageCount < pageSize {
+		return ""
+	}
+
+	return strconv.Itoa(position + pageCount)
+}
+
+// PaginateInlineResults returns the page of results for the inline query offset together with the next offset.
+func PaginateInlineResults[T any](items []T, offset string, pageSize int) ([]T, string) {
+	pageSize = min(max(pageSize, 1), MaxInlineResults)
+	position := min(InlineOffset(offset), len(items))
+	end := min(position+pageSize, len(items))
+
+	if end == len(items) {
+		return items[position:end], ""
+	}
+
+	return items[position:end], NextInlineOffset(position, pageSize, end-position)
+}
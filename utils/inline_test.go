@@ -0,0 +1,29 @@
+package utils
+
+import "testing"
+
+func TestPaginateInlineResults(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	page, next := PaginateInlineResults(items, "", 2)
+
+	if len(page) != 2 || page[0] != 1 || next != "2" {
+		t.Fatalf("unexpected first page %v with offset %q", page, next)
+	}
+
+	page, next = PaginateInlineResults(items, next, 2)
+
+	if len(page) != 2 || page[0] != 3 || next != "4" {
+		t.Fatalf("unexpected second page %v with offset %q", page, next)
+	}
+
+	page, next = PaginateInlineResults(items, next, 2)
+
+	if len(page) != 1 || page[0] != 5 || next != "" {
+		t.Fatalf("unexpected last page %v with offset %q", page, next)
+	}
+
+	if page, _ = PaginateInlineResults(items, "100", 2); len(page) != 0 {
+		t.Fatalf("expected empty page, got %v", page)
+	}
+}